@@ -0,0 +1,15 @@
+// Command picpreview serves a web page reproducing the Plug-in Chart dialog
+// for the go-chart example's configurations.
+//
+//	picpreview -addr localhost:8080 config/go-chart.xml
+package main
+
+import (
+	_ "github.com/PreciselyData/compose-chart-api/example/go-chart/engine"
+
+	"github.com/PreciselyData/compose-chart-api/pic/preview"
+)
+
+func main() {
+	preview.Main()
+}
@@ -0,0 +1,15 @@
+// Command picrender creates the chart images of the go-chart example's
+// configurations from their cfg files, without Designer or Generate.
+//
+//	picrender -o images -width 4in -height 3in -format png config/go-chart-line.cfg
+package main
+
+import (
+	_ "github.com/PreciselyData/compose-chart-api/example/go-chart/engine"
+
+	"github.com/PreciselyData/compose-chart-api/pic/render"
+)
+
+func main() {
+	render.Main()
+}
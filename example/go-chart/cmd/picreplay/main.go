@@ -0,0 +1,15 @@
+// Command picreplay replays capture files with the go-chart example engine,
+// reporting whether each image is the same as the captured one.
+//
+//	picreplay [-o dir] capture.json...
+package main
+
+import (
+	_ "github.com/PreciselyData/compose-chart-api/example/go-chart/engine"
+
+	"github.com/PreciselyData/compose-chart-api/pic/replay"
+)
+
+func main() {
+	replay.Main()
+}
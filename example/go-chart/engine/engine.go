@@ -0,0 +1,16 @@
+// Package engine is the go-chart example chart engine. It registers itself
+// with pic in its init function, so that the shared library and the
+// picrender, picreplay and picpreview commands only need to import it.
+package engine
+
+import "github.com/PreciselyData/compose-chart-api/pic"
+
+type client struct{}
+
+func (*client) NewBuilder(c *pic.Config) pic.Builder {
+	return newBuilder(c)
+}
+
+func init() {
+	pic.Register("go-chart", &client{})
+}
@@ -1,19 +1,17 @@
 package main
 
-import "github.com/PreciselyData/compose-chart-api/pic"
+import (
+	"github.com/PreciselyData/compose-chart-api/pic"
 
-type client struct{}
-
-func (*client) NewBuilder(c *pic.Config) pic.Builder {
-	return newBuilder(c)
-}
+	_ "github.com/PreciselyData/compose-chart-api/example/go-chart/engine"
+)
 
 func init() {
-	pic.SetClient(
-		&client{},
+	pic.SetOptions(
 		pic.Options{
 			LogLevel:    pic.LogInfo,
 			LogFileName: "go-chart.log",
+			LogMaxFiles: 5,
 		},
 	)
 }
@@ -0,0 +1,49 @@
+//go:build linux
+
+package pic
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// TestABI builds testdata/abi/engine with -buildmode=c-shared and runs the
+// C test host in testdata/abi against it, so that the exported functions,
+// the callbacks and the wide character conversions are tested as they are
+// used by Designer/Generate.
+func TestABI(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping ABI test in short mode")
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("skipping ABI test without a C compiler")
+	}
+	dir := t.TempDir()
+
+	lib := filepath.Join(dir, "libengine.so")
+	goTool := filepath.Join(runtime.GOROOT(), "bin", "go")
+	runCommand(t, goTool, "build", "-buildmode=c-shared", "-o", lib, "./testdata/abi/engine")
+
+	host := filepath.Join(dir, "host")
+	runCommand(t, cc, "-std=c99", "-Wall", "-Werror", "-I.", "-o", host, "testdata/abi/host.c", "-ldl")
+
+	font := filepath.Join(dir, "Gö Regular 😀.ttf")
+	if err := os.WriteFile(font, goregular.TTF, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runCommand(t, host, lib, dir)
+}
+
+func runCommand(t *testing.T, name string, args ...string) {
+	t.Helper()
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", filepath.Base(name), err, out)
+	}
+}
@@ -1,6 +1,10 @@
 package pic
 
-import "bytes"
+import (
+	"bytes"
+	"context"
+	"io"
+)
 
 // Builder creates a chart image.
 type Builder interface {
@@ -8,3 +12,21 @@ type Builder interface {
 	SetSize(width, height Twiplet, dpi int32)
 	Render() (*bytes.Buffer, error)
 }
+
+// ContextBuilder is a Builder that can be cancelled. If a Builder implements
+// ContextBuilder then RenderContext is called instead of Render, and the
+// context is cancelled when Options.RenderTimeout elapses.
+type ContextBuilder interface {
+	Builder
+	RenderContext(ctx context.Context) (*bytes.Buffer, error)
+}
+
+// StreamBuilder is a Builder that writes the image directly to the memory
+// returned to Designer/Generate, which avoids holding a second copy of a
+// large image. If a Builder implements StreamBuilder then RenderTo is called
+// instead of Render or RenderContext, and writes to w fail once
+// Options.RenderTimeout elapses.
+type StreamBuilder interface {
+	Builder
+	RenderTo(w io.Writer) error
+}
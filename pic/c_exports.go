@@ -4,7 +4,8 @@ package pic
 import "C"
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"unsafe"
 )
 
@@ -20,77 +21,64 @@ func EnchCreateImage(
 	callbackPtr unsafe.Pointer,
 	propertiesPtr, symbolsPtr *C.char,
 	imagePtr unsafe.Pointer,
-) (rc ReturnCode) {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Println("Unexpected failure:", r)
-			rc = Failed
-		}
-	}()
-
-	if client == nil {
-		log.Println("No implementation defined")
-		return NotImplemented
-	}
-
-	props := C.GoString(propertiesPtr)
-	syms := C.GoString(symbolsPtr)
-	img := (*Image)(imagePtr)
-
-	if options.LogInfo() {
-		log.Printf(
-			"INFO: Creating image: width=%v, height=%v, DPI=%v, format=%v, colorspace=%v\n"+
-				"[Properties]\n%s\n"+
-				"[Symbols]\n%s\n",
-			img.width, img.height, img.resolution, img.format, img.colorSpace,
-			props, syms,
-		)
-	}
-
-	if img.width == 0 || img.height == 0 {
-		log.Println("Zero dimensions supplied")
-		return InvalidValue
-	}
-
-	config := newConfig(callback{callbackPtr}, props, syms)
-	builder := client.NewBuilder(config)
-	if builder == nil {
-		log.Println("Configuration not supported")
-		return NotImplemented
-	}
-
-	builder.SetFormat(&img.format, &img.colorSpace)
-	builder.SetSize(img.width, img.height, img.resolution)
+) ReturnCode {
+	return createImage(
+		callback{callbackPtr},
+		C.GoString(propertiesPtr),
+		C.GoString(symbolsPtr),
+		(*Image)(imagePtr),
+	)
+}
 
-	buf, err := builder.Render()
+func createImage(r Resolver, props, syms string, img *Image) ReturnCode {
+	st := load()
+	spec := img.spec()
+	buf, err := st.createImage(r, props, syms, &spec, st.options)
+	img.format, img.colorSpace = spec.Format, spec.ColorSpace
 	if err != nil {
-		log.Println("Error rendering chart:", err)
-		return Failed
+		return ErrorCode(err)
 	}
+	img.imageDataPtr = buf.p
+	img.imageDataLen = uint32(buf.len)
+	return OK
+}
 
-	img.imageDataPtr = C.CBytes(buf.Bytes())
-	img.imageDataLen = uint32(buf.Len())
-
-	if options.LogInfo() {
-		log.Printf(
-			"INFO: Created image: size=%d, format=%v, colorspace=%v\n",
-			img.imageDataLen, img.format, img.colorSpace,
-		)
+func logFontCacheStats(l *slog.Logger) {
+	if !l.Enabled(context.Background(), slog.LevelInfo) {
+		return
 	}
-	return OK
+	fcs := fonts.stats()
+	l.Info(
+		"Font cache",
+		slog.Uint64("hits", fcs.hits),
+		slog.Uint64("misses", fcs.misses),
+		slog.Uint64("evictions", fcs.evictions),
+		slog.Int("fonts", fcs.fonts),
+		slog.Int64("size", fcs.size),
+	)
 }
 
 // EnchDestroyImage is called by Designer/Generate to destroy the chart image
 // data created by EnchCreateImage.
 //export EnchDestroyImage
 func EnchDestroyImage(imagePtr unsafe.Pointer) ReturnCode {
-	img := (*Image)(imagePtr)
-	if options.LogInfo() {
-		log.Printf(
-			"INFO: Destroying image: size=%d, format=%v, colorspace=%v\n",
-			img.imageDataLen, img.format, img.colorSpace,
-		)
-	}
+	return destroyImage((*Image)(imagePtr))
+}
+
+func destroyImage(img *Image) (rc ReturnCode) {
+	defer func() {
+		if r := recover(); r != nil {
+			cr := &crashReport{function: "EnchDestroyImage", panic: newPanicError(r)}
+			cr.save(load().options, rootLogger())
+			rc = Failed
+		}
+	}()
+	rootLogger().Info(
+		"Destroying image",
+		slog.Int("size", int(img.imageDataLen)),
+		slog.String("format", img.format.String()),
+		slog.String("colorspace", img.colorSpace.String()),
+	)
 	C.free(img.imageDataPtr)
 	return OK
 }
@@ -101,8 +89,13 @@ func EnchDestroyImage(imagePtr unsafe.Pointer) ReturnCode {
 // some garbage collection.
 //export EnchTerminate
 func EnchTerminate() ReturnCode {
-	if options.LogInfo() {
-		log.Println("INFO: Terminating")
-	}
+	return terminate()
+}
+
+func terminate() ReturnCode {
+	l := rootLogger()
+	logFontCacheStats(l)
+	stopMetrics(load().options)
+	l.Info("Terminating")
 	return Failed
 }
@@ -0,0 +1,325 @@
+package pic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+type renderClient func(c *Config) (*bytes.Buffer, error)
+
+type renderBuilder struct {
+	*Config
+	render renderClient
+}
+
+func (rc renderClient) NewBuilder(c *Config) Builder {
+	return &renderBuilder{Config: c, render: rc}
+}
+
+func (*renderBuilder) SetFormat(format *ImageFormat, colorSpace *ColorSpace) {
+}
+
+func (*renderBuilder) SetSize(width, height Twiplet, dpi int32) {
+}
+
+func (b *renderBuilder) Render() (*bytes.Buffer, error) {
+	return b.render(b.Config)
+}
+
+func withClient(c Client, o Options) (restore func()) {
+	old := load()
+	update(func(s *state) {
+		s.client = c
+		s.options = o
+	})
+	return func() {
+		update(func(s *state) {
+			s.client = old.client
+			s.options = old.options
+		})
+	}
+}
+
+func testImage() *Image {
+	return &Image{
+		format:     PNG,
+		colorSpace: RGB,
+		width:      Twiplet(Inch),
+		height:     Twiplet(Inch),
+		resolution: 96,
+	}
+}
+
+func TestCreateImage(t *testing.T) {
+	defer withClient(renderClient(func(c *Config) (*bytes.Buffer, error) {
+		return bytes.NewBufferString(c.Value("title").Text()), nil
+	}), Options{})()
+	img := testImage()
+	rc := createImage(newMockCallback(), "title=chart", "", img)
+	assertEqual(t, rc, OK)
+	assertEqual(t, img.imageDataLen, uint32(5))
+	assertEqual(t, destroyImage(img), OK)
+}
+
+func TestCreateImageNoClient(t *testing.T) {
+	defer withClient(nil, Options{})()
+	assertEqual(t, createImage(newMockCallback(), "", "", testImage()), NotImplemented)
+}
+
+func TestCreateImageRegisteredClient(t *testing.T) {
+	defer withClient(nil, Options{})()
+	engine := func(name string) Client {
+		return renderClient(func(c *Config) (*bytes.Buffer, error) {
+			return bytes.NewBufferString(name), nil
+		})
+	}
+	Register("engine1", engine("one"))
+	Register("engine2", engine("second"))
+	defer Register("engine1", nil)
+	defer Register("engine2", nil)
+
+	img := testImage()
+	assertEqual(t, createImage(newMockCallback(), "engine=engine1", "", img), OK)
+	assertEqual(t, img.imageDataLen, uint32(3))
+	destroyImage(img)
+	assertEqual(t, createImage(newMockCallback(), "engine=engine2", "", img), OK)
+	assertEqual(t, img.imageDataLen, uint32(6))
+	destroyImage(img)
+	assertEqual(t, createImage(newMockCallback(), "engine=engine3", "", img), NotImplemented)
+
+	defer withClient(engine("default"), Options{})()
+	assertEqual(t, createImage(newMockCallback(), "engine=engine3", "", img), NotImplemented)
+	assertEqual(t, createImage(newMockCallback(), "config=pie", "", img), OK)
+	assertEqual(t, img.imageDataLen, uint32(7))
+	destroyImage(img)
+
+	Register("engine1", nil)
+	Register("engine2", nil)
+	assertEqual(t, createImage(newMockCallback(), "engine=engine3", "", img), OK)
+	assertEqual(t, img.imageDataLen, uint32(7))
+	destroyImage(img)
+}
+
+func TestCreateImageZeroSize(t *testing.T) {
+	defer withClient(mockClient{}, Options{})()
+	img := testImage()
+	img.width = 0
+	assertEqual(t, createImage(newMockCallback(), "", "", img), InvalidValue)
+}
+
+func TestCreateImagePanic(t *testing.T) {
+	defer withClient(renderClient(func(c *Config) (*bytes.Buffer, error) {
+		panic("oops")
+	}), Options{CrashDir: t.TempDir()})()
+	assertEqual(t, createImage(newMockCallback(), "", "", testImage()), Failed)
+}
+
+func TestCreateImageStrict(t *testing.T) {
+	tests := []struct {
+		props  string
+		strict bool
+		rc     ReturnCode
+	}{
+		{"num=foo", false, OK},
+		{"num=foo", true, InvalidDataString},
+		{"num=1.5", true, OK},
+		{"num=1.5\ncolor=1,2,3", true, InvalidValue},
+	}
+	render := renderClient(func(c *Config) (*bytes.Buffer, error) {
+		c.Number("num")
+		c.Color("color")
+		return &bytes.Buffer{}, nil
+	})
+	for _, test := range tests {
+		restore := withClient(render, Options{Strict: test.strict})
+		img := testImage()
+		rc := createImage(newMockCallback(), test.props, "", img)
+		restore()
+		assertEqual(t, rc, test.rc)
+		if rc == OK {
+			destroyImage(img)
+		}
+	}
+}
+
+func TestCreateImageError(t *testing.T) {
+	defer withClient(renderClient(func(c *Config) (*bytes.Buffer, error) {
+		return nil, &Error{Code: MissingProperty, Property: "title"}
+	}), Options{})()
+	assertEqual(t, createImage(newMockCallback(), "", "", testImage()), MissingProperty)
+}
+
+func TestCreateImageErrorWithoutCode(t *testing.T) {
+	defer withClient(renderClient(func(c *Config) (*bytes.Buffer, error) {
+		return nil, &Error{Err: errors.New("no code")}
+	}), Options{})()
+	img := testImage()
+	assertEqual(t, createImage(newMockCallback(), "", "", img), Failed)
+	assertEqual(t, img.imageDataLen, uint32(0))
+}
+
+type contextBuilder struct {
+	renderBuilder
+	cancelled chan struct{}
+}
+
+func (b *contextBuilder) RenderContext(ctx context.Context) (*bytes.Buffer, error) {
+	<-ctx.Done()
+	close(b.cancelled)
+	return nil, ctx.Err()
+}
+
+type contextClient chan struct{}
+
+func (cc contextClient) NewBuilder(c *Config) Builder {
+	return &contextBuilder{cancelled: cc}
+}
+
+func TestCreateImageTimeout(t *testing.T) {
+	release := make(chan struct{})
+	resolved := make(chan error)
+	defer withClient(renderClient(func(c *Config) (*bytes.Buffer, error) {
+		<-release
+		_, err := c.NumberE("num")
+		resolved <- err
+		return &bytes.Buffer{}, nil
+	}), Options{RenderTimeout: 10 * time.Millisecond})()
+	rc := createImage(newMockCallback(), "num=1", "", testImage())
+	assertEqual(t, rc, Failed)
+	close(release)
+	assertEqual(t, errors.Is(<-resolved, errResolverClosed), true)
+}
+
+func TestCreateImageContextTimeout(t *testing.T) {
+	cancelled := make(chan struct{})
+	defer withClient(contextClient(cancelled), Options{RenderTimeout: 10 * time.Millisecond})()
+	assertEqual(t, createImage(newMockCallback(), "", "", testImage()), Failed)
+	<-cancelled
+}
+
+func TestCreateImageWithinTimeout(t *testing.T) {
+	defer withClient(mockClient{}, Options{RenderTimeout: time.Minute})()
+	img := testImage()
+	assertEqual(t, createImage(newMockCallback(), "", "", img), OK)
+	destroyImage(img)
+}
+
+func TestCreateImageConcurrent(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "test")
+	if err != nil {
+		t.Fatalf("Failed to create temp file")
+	}
+	tmpfile.Close()
+	defer func() {
+		log.SetOutput(ioutil.Discard)
+		os.Remove(tmpfile.Name())
+	}()
+
+	render := renderClient(func(c *Config) (*bytes.Buffer, error) {
+		var buf bytes.Buffer
+		for _, vals := range c.DataValues() {
+			for _, val := range vals {
+				fmt.Fprintf(&buf, "%v,", c.ResolveNumber(val))
+			}
+		}
+		fs := c.ResolveFont(c.Font("font"))
+		fmt.Fprintf(&buf, "%s,%s", fs.Typeface, c.Value("title").Text())
+		return &buf, nil
+	})
+	defer withClient(nil, Options{})()
+	SetOptions(Options{LogLevel: LogInfo, LogFileName: tmpfile.Name()})
+	Register("stress", render)
+	defer Register("stress", nil)
+
+	const calls = 200
+	var wg sync.WaitGroup
+	errs := make(chan error, calls)
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p := fmt.Sprintf("engine=stress\ntitle=chart%d\ndata.values=1,2|3,4\n"+
+				"font=%cfCAFE000000000000000000000000F00D|0,0,0,100|0", i, ascESC)
+			img := testImage()
+			if rc := createImage(newMockCallback(), p, "", img); rc != OK {
+				errs <- fmt.Errorf("call %d returned %v", i, rc)
+				return
+			}
+			got := string(img.data())
+			if want := fmt.Sprintf("1,2,3,4,mockfont,chart%d", i); got != want {
+				errs <- fmt.Errorf("call %d rendered '%s', want '%s'", i, got, want)
+			}
+			destroyImage(img)
+		}(i)
+	}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		defer Register(fmt.Sprintf("other%d", i), nil)
+		go func(i int) {
+			defer wg.Done()
+			Register(fmt.Sprintf("other%d", i), mockClient{})
+			SetOptions(Options{LogLevel: LogInfo})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+type streamBuilder struct {
+	renderBuilder
+	chunks int
+	delay  time.Duration
+}
+
+func (b *streamBuilder) RenderTo(w io.Writer) error {
+	chunk := bytes.Repeat([]byte{'x'}, 10000)
+	for i := 0; i < b.chunks; i++ {
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+		time.Sleep(b.delay)
+	}
+	return nil
+}
+
+type streamClient streamBuilder
+
+func (sc streamClient) NewBuilder(c *Config) Builder {
+	return &streamBuilder{chunks: sc.chunks, delay: sc.delay}
+}
+
+func TestCreateImageStream(t *testing.T) {
+	defer withClient(streamClient{chunks: 100}, Options{})()
+	img := testImage()
+	assertEqual(t, createImage(newMockCallback(), "", "", img), OK)
+	assertEqual(t, img.imageDataLen, uint32(1000000))
+	assertEqual(t, bytes.Count(img.data(), []byte{'x'}), 1000000)
+	destroyImage(img)
+}
+
+func TestCreateImageStreamTimeout(t *testing.T) {
+	defer withClient(
+		streamClient{chunks: 1 << 30, delay: time.Millisecond},
+		Options{RenderTimeout: 10 * time.Millisecond},
+	)()
+	assertEqual(t, createImage(newMockCallback(), "", "", testImage()), Failed)
+}
+
+func TestCreateImageNoData(t *testing.T) {
+	defer withClient(renderClient(func(c *Config) (*bytes.Buffer, error) {
+		return nil, nil
+	}), Options{})()
+	assertEqual(t, createImage(newMockCallback(), "", "", testImage()), Failed)
+}
@@ -6,6 +6,7 @@ import "C"
 import (
 	"errors"
 	"fmt"
+	"time"
 	"unsafe"
 )
 
@@ -79,6 +80,13 @@ type NumberFormat struct {
 	ThousandsSeparator, DecimalPoint rune
 }
 
+// DateTimeFormat defines how dates and times should be formatted for display.
+type DateTimeFormat struct {
+	MonthNames, WeekDayNames                    []string
+	AM, PM                                      string
+	ShortDateFormat, LongDateFormat, TimeFormat string
+}
+
 // Image represents the chart image requirements.
 type Image struct {
 	format       ImageFormat    // [In/Out] Preferred image format.
@@ -91,11 +99,26 @@ type Image struct {
 	imageDataLen uint32         // [Out] Number of bytes in the image data.
 }
 
+func (img *Image) spec() ImageSpec {
+	return ImageSpec{
+		Width:      img.width,
+		Height:     img.height,
+		DPI:        img.resolution,
+		Format:     img.format,
+		ColorSpace: img.colorSpace,
+	}
+}
+
+// data gets a copy of the image data.
+func (img *Image) data() []byte {
+	return C.GoBytes(img.imageDataPtr, C.int(img.imageDataLen))
+}
+
 type callback struct {
 	p unsafe.Pointer
 }
 
-func (c callback) integer(s string) (int32, error) {
+func (c callback) Integer(s string) (int32, error) {
 	var i C.int
 	cs := C.CString(s)
 	rc := C.EnchGetInteger(c.p, cs, &i)
@@ -110,7 +133,7 @@ func (c callback) integer(s string) (int32, error) {
 	return int32(i), nil
 }
 
-func (c callback) number(s string) (float64, error) {
+func (c callback) Number(s string) (float64, error) {
 	var d C.double
 	cs := C.CString(s)
 	rc := C.EnchGetNumber(c.p, cs, &d)
@@ -125,13 +148,85 @@ func (c callback) number(s string) (float64, error) {
 	return float64(d), nil
 }
 
-func (c callback) numberFormat() NumberFormat {
+func (c callback) Date(s string) (time.Time, error) {
+	var d C.EnchDate
+	cs := C.CString(s)
+	rc := C.EnchGetDate(c.p, cs, &d)
+	C.free(unsafe.Pointer(cs))
+	if rc != C.ENCHRC_OK {
+		err := fmt.Errorf(
+			"invalid date format '%s', error %v",
+			s, ReturnCode(rc),
+		)
+		return time.Time{}, err
+	}
+	return newDate(int(d.nYear), int(d.nMonth), int(d.nDay)), nil
+}
+
+func (c callback) TimeOfDay(s string) (time.Time, error) {
+	var t C.EnchTime
+	cs := C.CString(s)
+	rc := C.EnchGetTime(c.p, cs, &t)
+	C.free(unsafe.Pointer(cs))
+	if rc != C.ENCHRC_OK {
+		err := fmt.Errorf(
+			"invalid time format '%s', error %v",
+			s, ReturnCode(rc),
+		)
+		return time.Time{}, err
+	}
+	return newTime(int(t.nHour), int(t.nMinute), int(t.nSecond)), nil
+}
+
+func (c callback) DataValue(s string, t DataType) (Datum, error) {
+	var dv C.EnchDataValue
+	cs := C.CString(s)
+	rc := C.EnchGetDataValue(c.p, cs, &dv, C.int(t))
+	C.free(unsafe.Pointer(cs))
+	if rc != C.ENCHRC_OK {
+		err := fmt.Errorf(
+			"invalid %v format '%s', error %v",
+			t, s, ReturnCode(rc),
+		)
+		return Datum{Type: NotSet}, err
+	}
+	d := Datum{Type: DataType(dv.nType)}
+	data := unsafe.Pointer(&dv.data)
+	switch d.Type {
+	case Neutral:
+	case Integer:
+		d.Integer = int32(*(*C.int)(data))
+	case Number, Currency:
+		d.Number = float64(*(*C.double)(data))
+	case Date:
+		date := (*C.EnchDate)(data)
+		d.Time = newDate(int(date.nYear), int(date.nMonth), int(date.nDay))
+	case Time:
+		tm := (*C.EnchTime)(data)
+		d.Time = newTime(int(tm.nHour), int(tm.nMinute), int(tm.nSecond))
+	default:
+		return Datum{Type: NotSet}, fmt.Errorf(
+			"unexpected data type %v for value '%s'", d.Type, s,
+		)
+	}
+	return d, nil
+}
+
+// newDate creates a date value at midnight UTC.
+func newDate(year, month, day int) time.Time {
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// newTime creates a time value on 1st January of year 0 UTC, so that
+// midnight can be distinguished from the zero time.Time.
+func newTime(hour, min, sec int) time.Time {
+	return time.Date(0, time.January, 1, hour, min, sec, 0, time.UTC)
+}
+
+func (c callback) NumberFormat() NumberFormat {
 	var f C.EnchNumberFormat
 	if C.EnchGetNumberFormat(c.p, &f) == 0 {
-		return NumberFormat{
-			ThousandsSeparator: ',',
-			DecimalPoint:       '.',
-		}
+		return DefaultNumberFormat()
 	}
 	return NumberFormat{
 		ThousandsSeparator: rune(f.chThousandsSeparator),
@@ -139,7 +234,66 @@ func (c callback) numberFormat() NumberFormat {
 	}
 }
 
-func (c callback) fontResource(guid GUID) (*FontResource, error) {
+// DefaultNumberFormat gets the number format used when Designer/Generate
+// cannot supply one.
+func DefaultNumberFormat() NumberFormat {
+	return NumberFormat{
+		ThousandsSeparator: ',',
+		DecimalPoint:       '.',
+	}
+}
+
+func (c callback) DateTimeFormat() DateTimeFormat {
+	var f C.EnchDateTimeFormatUtf8
+	if C.EnchGetDateTimeFormat(c.p, &f) == 0 {
+		return DefaultDateTimeFormat()
+	}
+	dtf := DateTimeFormat{
+		MonthNames:      goStrings(f.ppszMonthNames, f.cMonthNames),
+		WeekDayNames:    goStrings(f.ppszWeekDayNames, f.cWeekDayNames),
+		AM:              C.GoString(f.pszAm),
+		PM:              C.GoString(f.pszPm),
+		ShortDateFormat: C.GoString(f.pszShortDateFormat),
+		LongDateFormat:  C.GoString(f.pszLongDateFormat),
+		TimeFormat:      C.GoString(f.pszTimeFormat),
+	}
+	C.EnchFreeDateTimeFormat(&f)
+	return dtf
+}
+
+// DefaultDateTimeFormat gets the date and time format used when
+// Designer/Generate cannot supply one.
+func DefaultDateTimeFormat() DateTimeFormat {
+	return DateTimeFormat{
+		MonthNames: []string{
+			"January", "February", "March", "April", "May", "June", "July",
+			"August", "September", "October", "November", "December",
+		},
+		WeekDayNames: []string{
+			"Sunday", "Monday", "Tuesday", "Wednesday",
+			"Thursday", "Friday", "Saturday",
+		},
+		AM:              "AM",
+		PM:              "PM",
+		ShortDateFormat: "M/d/yyyy",
+		LongDateFormat:  "dddd, MMMM d, yyyy",
+		TimeFormat:      "h:mm:ss tt",
+	}
+}
+
+func goStrings(p **C.char, n C.int) []string {
+	if p == nil || n <= 0 {
+		return nil
+	}
+	cs := (*[1 << 20]*C.char)(unsafe.Pointer(p))[:n:n]
+	s := make([]string, n)
+	for i := range cs {
+		s[i] = C.GoString(cs[i])
+	}
+	return s
+}
+
+func (c callback) FontResource(guid GUID) (*FontResource, error) {
 	var cfr C.EnchFontResourceUtf8
 	if guid.IsZero() {
 		if C.EnchGetFont(c.p, nil, &cfr) == 0 {
@@ -160,7 +314,7 @@ func (c callback) fontResource(guid GUID) (*FontResource, error) {
 	return fr, nil
 }
 
-func (c callback) fontStyle(guid GUID) (*FontStyle, error) {
+func (c callback) FontStyle(guid GUID) (*FontStyle, error) {
 	var csr C.EnchStyleResourceUtf8
 	if C.EnchGetStyle(c.p, (*C.uchar)(&guid[0]), &csr) == 0 {
 		return nil, fmt.Errorf("style not found for guid %v", guid)
@@ -20,6 +20,19 @@ typedef struct tagEnchStyleResourceUtf8
    unsigned short fsFlags; // combination of ENCH_Style* flags
 } EnchStyleResourceUtf8;
 
+typedef struct tagEnchDateTimeFormatUtf8
+{
+   char** ppszMonthNames;
+   int cMonthNames;
+   char** ppszWeekDayNames;
+   int cWeekDayNames;
+   char* pszAm;
+   char* pszPm;
+   char* pszShortDateFormat;
+   char* pszLongDateFormat;
+   char* pszTimeFormat;
+} EnchDateTimeFormatUtf8;
+
 ENCHRC EnchGetDataValue(void* pvCallback, const char* pszValue, EnchDataValue* pDataValue, int nExpectedType)
 {
    EnchCallback* pCallback = (EnchCallback*)pvCallback;
@@ -77,6 +90,38 @@ ENCHRC EnchGetNumber(void* pvCallback, const char* pszValue, double* pValue)
    return rc;
 }
 
+ENCHRC EnchGetDate(void* pvCallback, const char* pszValue, EnchDate* pValue)
+{
+   EnchDataValue dataValue;
+   ENCHRC rc = EnchGetDataValue(pvCallback, pszValue, &dataValue, ENCH_DataDate);
+   if (rc != ENCHRC_OK)
+   {
+      return rc;
+   }
+   if (dataValue.nType != ENCH_DataDate)
+   {
+      return ENCHRC_InvalidValue;
+   }
+   *pValue = dataValue.data.dateValue;
+   return rc;
+}
+
+ENCHRC EnchGetTime(void* pvCallback, const char* pszValue, EnchTime* pValue)
+{
+   EnchDataValue dataValue;
+   ENCHRC rc = EnchGetDataValue(pvCallback, pszValue, &dataValue, ENCH_DataTime);
+   if (rc != ENCHRC_OK)
+   {
+      return rc;
+   }
+   if (dataValue.nType != ENCH_DataTime)
+   {
+      return ENCHRC_InvalidValue;
+   }
+   *pValue = dataValue.data.timeValue;
+   return rc;
+}
+
 int EnchGetNumberFormat(void* pvCallback, EnchNumberFormat* pNumberFormat)
 {
    EnchCallback* pCallback = (EnchCallback*)pvCallback;
@@ -121,6 +166,77 @@ int EnchGetStyle(void* pvCallback, const unsigned char* pGuid, EnchStyleResource
    return rc;
 }
 
+char* Utf8FromWideCharOrNull(const wchar_t* pwsz)
+{
+   return pwsz ? Utf8FromWideChar(pwsz) : NULL;
+}
+
+char** Utf8ArrayFromWideChar(wchar_t** ppwsz, int cwsz)
+{
+   if (!ppwsz || cwsz <= 0)
+   {
+      return NULL;
+   }
+   char** ppsz = (char**)malloc(cwsz * sizeof(char*));
+   for (int i = 0; i < cwsz; i++)
+   {
+      ppsz[i] = Utf8FromWideCharOrNull(ppwsz[i]);
+   }
+   return ppsz;
+}
+
+void Utf8ArrayFree(char** ppsz, int csz)
+{
+   if (ppsz)
+   {
+      for (int i = 0; i < csz; i++)
+      {
+         free(ppsz[i]);
+      }
+      free(ppsz);
+   }
+}
+
+int EnchGetDateTimeFormat(void* pvCallback, EnchDateTimeFormatUtf8* pDateTimeFormat)
+{
+   EnchCallback* pCallback = (EnchCallback*)pvCallback;
+   EnchDateTimeFormat dtf;
+   if (!pCallback->pfnGetDateTimeFormat)
+   {
+      return 0;
+   }
+   memset(&dtf, 0, sizeof(dtf));
+   int rc = pCallback->pfnGetDateTimeFormat(pCallback, &dtf);
+   if (rc)
+   {
+      pDateTimeFormat->ppszMonthNames = Utf8ArrayFromWideChar(dtf.ppszMonthNames, dtf.cMonthNames);
+      pDateTimeFormat->cMonthNames = pDateTimeFormat->ppszMonthNames ? dtf.cMonthNames : 0;
+      pDateTimeFormat->ppszWeekDayNames = Utf8ArrayFromWideChar(dtf.ppszWeekDayNames, dtf.cWeekDayNames);
+      pDateTimeFormat->cWeekDayNames = pDateTimeFormat->ppszWeekDayNames ? dtf.cWeekDayNames : 0;
+      pDateTimeFormat->pszAm = Utf8FromWideCharOrNull(dtf.pszAm);
+      pDateTimeFormat->pszPm = Utf8FromWideCharOrNull(dtf.pszPm);
+      pDateTimeFormat->pszShortDateFormat = Utf8FromWideCharOrNull(dtf.pszShortDateFormat);
+      pDateTimeFormat->pszLongDateFormat = Utf8FromWideCharOrNull(dtf.pszLongDateFormat);
+      pDateTimeFormat->pszTimeFormat = Utf8FromWideCharOrNull(dtf.pszTimeFormat);
+      if (pCallback->pfnFreeDateTimeFormat)
+      {
+         pCallback->pfnFreeDateTimeFormat(&dtf);
+      }
+   }
+   return rc;
+}
+
+void EnchFreeDateTimeFormat(EnchDateTimeFormatUtf8* pDateTimeFormat)
+{
+   Utf8ArrayFree(pDateTimeFormat->ppszMonthNames, pDateTimeFormat->cMonthNames);
+   Utf8ArrayFree(pDateTimeFormat->ppszWeekDayNames, pDateTimeFormat->cWeekDayNames);
+   free(pDateTimeFormat->pszAm);
+   free(pDateTimeFormat->pszPm);
+   free(pDateTimeFormat->pszShortDateFormat);
+   free(pDateTimeFormat->pszLongDateFormat);
+   free(pDateTimeFormat->pszTimeFormat);
+}
+
 wchar_t* Utf8ToWideChar(const char* pszUtf8)
 {
    // by looking at the first byte determine how many bytes need reading
@@ -196,6 +312,11 @@ wchar_t* Utf8ToWideChar(const char* pszUtf8)
             // This is an error as unicode cant be more than this
             pwc[iCountOut++] = 0xFFFD; // put in the undefined
          }
+         else if (sizeof(wchar_t) > 2)
+         {
+            // wchar_t is UTF-32 (Linux/UNIX) so the char needs no surrogates
+            pwc[iCountOut++] = (wchar_t)uiUnicode;
+         }
          else
          {
             // put the char in two bytes
@@ -224,7 +345,12 @@ char* Utf8FromWideChar(const wchar_t* pwsz)
 
    for (int iCounter = 0; iCounter < cwch; iCounter++)
    {
-      uiUnicode = (*pwch & 0xFFFF);
+      // wchar_t is UTF-16 on Windows but UTF-32 on Linux/UNIX
+      uiUnicode = (unsigned int)*pwch;
+      if (sizeof(wchar_t) == 2)
+      {
+         uiUnicode &= 0xFFFF;
+      }
 
       // If this is a surrogate pair
       if ( ( uiUnicode >= 0xD800 ) && ( uiUnicode <= 0xDBFF ) )
@@ -0,0 +1,322 @@
+package pic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Capture is the record of a call to EnchCreateImage, written to a file in
+// Options.CaptureDir. It holds everything needed to create the image again
+// without Designer/Generate: the configuration, the image requirements,
+// every answer given by Designer/Generate, and the contents of the font
+// files. The replay package creates the image again from a Capture.
+type Capture struct {
+	Time          time.Time
+	Properties    string
+	Symbols       string
+	Image         ImageSpec
+	Strict        bool          `json:",omitempty"`
+	RenderTimeout time.Duration `json:",omitempty"`
+
+	// Redacted is set if customer data was redacted from the capture, as
+	// specified by Options.Redact, in which case the image created from the
+	// capture differs from that of the captured call.
+	Redacted bool `json:",omitempty"`
+
+	// The answers given by Designer/Generate, by question.
+	Integers       map[string]CapturedValue              `json:",omitempty"`
+	Numbers        map[string]CapturedValue              `json:",omitempty"`
+	Dates          map[string]CapturedValue              `json:",omitempty"`
+	Times          map[string]CapturedValue              `json:",omitempty"`
+	DataValues     map[DataType]map[string]CapturedValue `json:",omitempty"`
+	NumberFormat   *NumberFormat                         `json:",omitempty"`
+	DateTimeFormat *DateTimeFormat                       `json:",omitempty"`
+	FontResources  map[string]CapturedFont               `json:",omitempty"` // By GUID.
+	FontStyles     map[string]CapturedFont               `json:",omitempty"` // By GUID.
+	FontFiles      map[string][]byte                     `json:",omitempty"` // By file name.
+
+	// The outcome of the call.
+	ReturnCode        ReturnCode
+	Error             string `json:",omitempty"`
+	CreatedFormat     ImageFormat
+	CreatedColorSpace ColorSpace
+	ImageSize         int
+	ImageHash         string `json:",omitempty"` // SHA-256 of the image data.
+}
+
+// CapturedValue is the answer given by Designer/Generate when asked to
+// convert a data value.
+type CapturedValue struct {
+	Datum
+	Error string `json:",omitempty"`
+}
+
+// Err gets the error returned by Designer/Generate, if any.
+func (v CapturedValue) Err() error {
+	if v.Error == "" {
+		return nil
+	}
+	return errors.New(v.Error)
+}
+
+// CapturedFont is the answer given by Designer/Generate when asked for a
+// font resource or font style. Color and Underline are only set for styles.
+type CapturedFont struct {
+	Typeface   string
+	PointSize  float64
+	Attributes FontAttribute
+	Filename   string
+	Color      *Color `json:",omitempty"`
+	Underline  bool   `json:",omitempty"`
+	Error      string `json:",omitempty"`
+}
+
+// Err gets the error returned by Designer/Generate, if any.
+func (f CapturedFont) Err() error {
+	if f.Error == "" {
+		return nil
+	}
+	return errors.New(f.Error)
+}
+
+// FontResource creates the font resource given by Designer/Generate.
+func (f CapturedFont) FontResource() *FontResource {
+	return &FontResource{
+		Typeface:   f.Typeface,
+		PointSize:  f.PointSize,
+		Attributes: f.Attributes,
+		Filename:   f.Filename,
+	}
+}
+
+// FontStyle creates the font style given by Designer/Generate.
+func (f CapturedFont) FontStyle() *FontStyle {
+	fs := &FontStyle{
+		FontResource: f.FontResource(),
+		Color:        DefaultColor,
+		Underline:    f.Underline,
+	}
+	if f.Color != nil {
+		fs.Color = *f.Color
+	}
+	return fs
+}
+
+// ReadCapture reads a capture written by Write.
+func ReadCapture(r io.Reader) (*Capture, error) {
+	c := &Capture{}
+	if err := json.NewDecoder(r).Decode(c); err != nil {
+		return nil, fmt.Errorf("invalid capture: %w", err)
+	}
+	return c, nil
+}
+
+// Write writes the capture as JSON.
+func (c *Capture) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// recorder records the answers given by Designer/Generate in a Capture.
+type recorder struct {
+	Resolver
+	redact *redactor
+	mu     sync.Mutex
+	c      *Capture
+}
+
+func newRecorder(r Resolver, props, syms string, spec ImageSpec, o Options) *recorder {
+	rd := o.Redact.redactor()
+	return &recorder{
+		Resolver: r,
+		redact:   rd,
+		c: &Capture{
+			Time:          time.Now(),
+			Properties:    rd.properties(props),
+			Symbols:       rd.symbols(syms),
+			Image:         spec,
+			Strict:        o.Strict,
+			RenderTimeout: o.RenderTimeout,
+			Redacted:      rd != nil,
+		},
+	}
+}
+
+func (r *recorder) record(m *map[string]CapturedValue, s string, d Datum, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if *m == nil {
+		*m = make(map[string]CapturedValue)
+	}
+	(*m)[r.redact.value(s)] = r.capturedValue(d, err)
+}
+
+func (r *recorder) capturedValue(d Datum, err error) CapturedValue {
+	v := CapturedValue{Datum: r.redact.datum(d)}
+	if err != nil {
+		v.Error = r.redact.error(err)
+	}
+	return v
+}
+
+func (r *recorder) Integer(s string) (int32, error) {
+	i, err := r.Resolver.Integer(s)
+	r.record(&r.c.Integers, s, Datum{Type: Integer, Integer: i}, err)
+	return i, err
+}
+
+func (r *recorder) Number(s string) (float64, error) {
+	n, err := r.Resolver.Number(s)
+	r.record(&r.c.Numbers, s, Datum{Type: Number, Number: n}, err)
+	return n, err
+}
+
+func (r *recorder) Date(s string) (time.Time, error) {
+	d, err := r.Resolver.Date(s)
+	r.record(&r.c.Dates, s, Datum{Type: Date, Time: d}, err)
+	return d, err
+}
+
+func (r *recorder) TimeOfDay(s string) (time.Time, error) {
+	t, err := r.Resolver.TimeOfDay(s)
+	r.record(&r.c.Times, s, Datum{Type: Time, Time: t}, err)
+	return t, err
+}
+
+func (r *recorder) DataValue(s string, t DataType) (Datum, error) {
+	d, err := r.Resolver.DataValue(s, t)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.c.DataValues == nil {
+		r.c.DataValues = make(map[DataType]map[string]CapturedValue)
+	}
+	if r.c.DataValues[t] == nil {
+		r.c.DataValues[t] = make(map[string]CapturedValue)
+	}
+	r.c.DataValues[t][r.redact.value(s)] = r.capturedValue(d, err)
+	return d, err
+}
+
+func (r *recorder) NumberFormat() NumberFormat {
+	nf := r.Resolver.NumberFormat()
+	r.mu.Lock()
+	r.c.NumberFormat = &nf
+	r.mu.Unlock()
+	return nf
+}
+
+func (r *recorder) DateTimeFormat() DateTimeFormat {
+	dtf := r.Resolver.DateTimeFormat()
+	r.mu.Lock()
+	r.c.DateTimeFormat = &dtf
+	r.mu.Unlock()
+	return dtf
+}
+
+func (r *recorder) FontResource(guid GUID) (*FontResource, error) {
+	fr, err := r.Resolver.FontResource(guid)
+	var cf CapturedFont
+	if err != nil {
+		cf.Error = err.Error()
+	} else {
+		cf = CapturedFont{
+			Typeface:   fr.Typeface,
+			PointSize:  fr.PointSize,
+			Attributes: fr.Attributes,
+			Filename:   fr.Filename,
+		}
+	}
+	r.recordFont(&r.c.FontResources, guid, cf)
+	return fr, err
+}
+
+func (r *recorder) FontStyle(guid GUID) (*FontStyle, error) {
+	fs, err := r.Resolver.FontStyle(guid)
+	var cf CapturedFont
+	if err != nil {
+		cf.Error = err.Error()
+	} else {
+		color := fs.Color
+		cf = CapturedFont{
+			Typeface:   fs.Typeface,
+			PointSize:  fs.PointSize,
+			Attributes: fs.Attributes,
+			Filename:   fs.Filename,
+			Color:      &color,
+			Underline:  fs.Underline,
+		}
+	}
+	r.recordFont(&r.c.FontStyles, guid, cf)
+	return fs, err
+}
+
+// recordFont records the font and the contents of its file.
+func (r *recorder) recordFont(m *map[string]CapturedFont, guid GUID, cf CapturedFont) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if *m == nil {
+		*m = make(map[string]CapturedFont)
+	}
+	(*m)[guid.String()] = cf
+	if cf.Filename == "" {
+		return
+	}
+	if _, ok := r.c.FontFiles[cf.Filename]; ok {
+		return
+	}
+	if b, err := os.ReadFile(cf.Filename); err == nil {
+		if r.c.FontFiles == nil {
+			r.c.FontFiles = make(map[string][]byte)
+		}
+		r.c.FontFiles[cf.Filename] = b
+	}
+}
+
+// save records the outcome of the call and writes the capture file.
+func (r *recorder) save(dir string, id uint64, spec ImageSpec, buf *cBuffer, err error, l *slog.Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.c
+	c.ReturnCode = ErrorCode(err)
+	c.CreatedFormat, c.CreatedColorSpace = spec.Format, spec.ColorSpace
+	if err != nil {
+		c.Error = r.redact.error(err)
+	}
+	if buf != nil {
+		data := buf.view()
+		sum := sha256.Sum256(data)
+		c.ImageSize = len(data)
+		c.ImageHash = hex.EncodeToString(sum[:])
+	}
+
+	name := filepath.Join(dir, fmt.Sprintf(
+		"capture-%s-%d-%d.json", c.Time.Format("20060102-150405"), os.Getpid(), id,
+	))
+	if err := writeCapture(name, c); err != nil {
+		l.Error("Failed to write capture file", slog.Any("error", err))
+		return
+	}
+	l.Info("Captured call", slog.String("file", name))
+}
+
+func writeCapture(name string, c *Capture) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := c.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,107 @@
+package pic
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// fontFileCallback gives fonts with a real font file.
+type fontFileCallback struct {
+	*mockCallback
+	filename string
+}
+
+func (fc fontFileCallback) FontResource(guid GUID) (*FontResource, error) {
+	fr, err := fc.mockCallback.FontResource(guid)
+	fr.Filename = fc.filename
+	return fr, err
+}
+
+func readCaptureFile(t *testing.T, dir string) *Capture {
+	names, _ := filepath.Glob(filepath.Join(dir, "capture-*.json"))
+	if len(names) != 1 {
+		t.Fatalf("Expected one capture file, found %d", len(names))
+	}
+	f, err := os.Open(names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	c, err := ReadCapture(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCapture(t *testing.T) {
+	dir := t.TempDir()
+	fontFile := filepath.Join(dir, "goregular.ttf")
+	if err := os.WriteFile(fontFile, goregular.TTF, 0644); err != nil {
+		t.Fatal(err)
+	}
+	captureDir := filepath.Join(dir, "captures")
+	os.Mkdir(captureDir, 0755)
+
+	defer withClient(renderClient(func(c *Config) (*bytes.Buffer, error) {
+		c.Integer("size")
+		c.Number("ratio")
+		c.Resolve(c.Value("value"))
+		c.NumberFormat()
+		c.ResolveFont(c.Font("font"))
+		return bytes.NewBufferString("chart"), nil
+	}), Options{CaptureDir: captureDir, Strict: true})()
+
+	props := fmt.Sprintf(
+		"size=42\nratio=1.5\nvalue=\x10v\nfont=%cfCAFE000000000000000000000000F00D|0,0,0,100|0",
+		ascESC,
+	)
+	syms := "v=\x1bi7"
+	img := testImage()
+	rc := createImage(fontFileCallback{newMockCallback(), fontFile}, props, syms, img)
+	assertEqual(t, rc, OK)
+	destroyImage(img)
+
+	c := readCaptureFile(t, captureDir)
+	assertEqual(t, c.Properties, props)
+	assertEqual(t, c.Symbols, syms)
+	assertEqual(t, c.Image, testImage().spec())
+	assertEqual(t, c.Strict, true)
+	assertEqual(t, c.Integers["42"].Integer, int32(42))
+	assertEqual(t, c.Numbers["1.5"].Number, 1.5)
+	assertEqual(t, c.DataValues[Integer]["\x1bi7"].Integer, int32(7))
+	assertEqual(t, c.NumberFormat != nil, true)
+	fr := c.FontResources["CAFE000000000000000000000000F00D"]
+	assertEqual(t, fr.Typeface, "mockfont")
+	assertEqual(t, fr.Filename, fontFile)
+	assertEqual(t, bytes.Equal(c.FontFiles[fontFile], goregular.TTF), true)
+	assertEqual(t, c.ReturnCode, OK)
+	assertEqual(t, c.ImageSize, 5)
+	sum := sha256.Sum256([]byte("chart"))
+	assertEqual(t, c.ImageHash, hex.EncodeToString(sum[:]))
+}
+
+func TestCaptureFailure(t *testing.T) {
+	dir := t.TempDir()
+	defer withClient(renderClient(func(c *Config) (*bytes.Buffer, error) {
+		c.Integer("size")
+		return bytes.NewBufferString("chart"), nil
+	}), Options{CaptureDir: dir, Strict: true})()
+
+	img := testImage()
+	rc := createImage(newMockCallback(), "size=big", "", img)
+	assertEqual(t, rc, InvalidDataString)
+
+	c := readCaptureFile(t, dir)
+	assertEqual(t, c.ReturnCode, InvalidDataString)
+	assertEqual(t, c.Error != "", true)
+	assertEqual(t, c.Integers["big"].Err() != nil, true)
+	assertEqual(t, c.ImageHash, "")
+}
@@ -0,0 +1,63 @@
+package pic
+
+// #include <stdlib.h>
+// #include <string.h>
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// cBuffer is an io.Writer that accumulates the image data in memory
+// allocated by C, so that the image can be returned to Designer/Generate
+// without being copied. The memory is freed by EnchDestroyImage.
+type cBuffer struct {
+	p        unsafe.Pointer
+	len, cap int
+}
+
+const minCBufferSize = 64 << 10
+
+func (b *cBuffer) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if need := b.len + len(p); need > b.cap {
+		size := 2 * b.cap
+		if size < need {
+			size = need
+		}
+		if size < minCBufferSize {
+			size = minCBufferSize
+		}
+		np := C.realloc(b.p, C.size_t(size))
+		if np == nil {
+			return 0, errors.New("out of memory allocating image data")
+		}
+		b.p, b.cap = np, size
+	}
+	dst := unsafe.Pointer(uintptr(b.p) + uintptr(b.len))
+	C.memcpy(dst, unsafe.Pointer(&p[0]), C.size_t(len(p)))
+	b.len += len(p)
+	return len(p), nil
+}
+
+func (b *cBuffer) free() {
+	C.free(b.p)
+	*b = cBuffer{}
+}
+
+// bytes gets a copy of the data in Go memory.
+func (b *cBuffer) bytes() []byte {
+	return C.GoBytes(b.p, C.int(b.len))
+}
+
+// view gets the data without copying it. The slice must not be used once
+// the buffer is freed.
+func (b *cBuffer) view() []byte {
+	if b.p == nil {
+		return nil
+	}
+	return unsafe.Slice((*byte)(b.p), b.len)
+}
@@ -0,0 +1,172 @@
+// Package cfg reads the cfg files that supply the default property values of
+// chart configurations. The defaults of configuration <id> of engine
+// <engine> are those of <engine>.cfg, common to every configuration of the
+// engine, overridden by those of <engine>-<id>.cfg, as merged by Designer.
+package cfg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Setting is a property=value pair from a cfg file.
+type Setting struct {
+	Name, Value string
+}
+
+// Settings holds the settings of a cfg file in order.
+type Settings []Setting
+
+// Parse parses the contents of a cfg file, one property=value pair per line.
+// Lines without '=' are ignored.
+func Parse(s string) Settings {
+	var settings Settings
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if name, value, ok := strings.Cut(line, "="); ok {
+			settings = append(settings, Setting{name, value})
+		}
+	}
+	return settings
+}
+
+// Read reads a cfg file.
+func Read(name string) (Settings, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(b)), nil
+}
+
+// Get gets the value of a property.
+func (s Settings) Get(name string) (string, bool) {
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i].Name == name {
+			return s[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// Merge returns the settings overridden by those of o. The order of the
+// settings is kept, with the settings only in o added at the end.
+func (s Settings) Merge(o Settings) Settings {
+	merged := make(Settings, 0, len(s)+len(o))
+	index := make(map[string]int, len(s)+len(o))
+	for _, settings := range []Settings{s, o} {
+		for _, setting := range settings {
+			if i, ok := index[setting.Name]; ok {
+				merged[i].Value = setting.Value
+				continue
+			}
+			index[setting.Name] = len(merged)
+			merged = append(merged, setting)
+		}
+	}
+	return merged
+}
+
+// String formats the settings as properties for EnchCreateImage.
+func (s Settings) String() string {
+	var b strings.Builder
+	for _, setting := range s {
+		b.WriteString(setting.Name)
+		b.WriteByte('=')
+		b.WriteString(setting.Value)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Configuration identifies the cfg file of a chart configuration.
+type Configuration struct {
+	Dir, Engine, ID string
+}
+
+// Name gets the name of the configuration, <engine>-<id>.
+func (c Configuration) Name() string {
+	return c.Engine + "-" + c.ID
+}
+
+// Load reads the defaults of the configuration, merging the cfg file of the
+// engine with that of the configuration.
+func (c Configuration) Load() (Settings, error) {
+	return Load(c.Dir, c.Engine, c.ID)
+}
+
+// Load reads the defaults of configuration id of the engine from the cfg
+// files in dir.
+func Load(dir, engine, id string) (Settings, error) {
+	common, err := Read(filepath.Join(dir, engine+".cfg"))
+	if err != nil {
+		return nil, err
+	}
+	settings, err := Read(filepath.Join(dir, engine+"-"+id+".cfg"))
+	if err != nil {
+		return nil, err
+	}
+	return common.Merge(settings), nil
+}
+
+// List finds the configurations with cfg files in dir, sorted by name. A
+// file <name>.cfg is the cfg file of a configuration if there is a file
+// <engine>.cfg where <name> is <engine>-<id>; the longest such engine name
+// is used.
+func List(dir string) ([]Configuration, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.cfg"))
+	if err != nil {
+		return nil, err
+	}
+	engines := make(map[string]bool, len(names))
+	for _, name := range names {
+		engines[strings.TrimSuffix(filepath.Base(name), ".cfg")] = true
+	}
+
+	var configs []Configuration
+	for name := range engines {
+		if c, ok := split(dir, name, func(engine string) bool { return engines[engine] }); ok {
+			configs = append(configs, c)
+		}
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no configurations found in %s", dir)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Name() < configs[j].Name()
+	})
+	return configs, nil
+}
+
+// Find gets the configuration of a cfg file, such as config/go-chart-pie.cfg,
+// finding its engine as List does.
+func Find(file string) (Configuration, error) {
+	dir, base := filepath.Split(file)
+	if dir == "" {
+		dir = "."
+	}
+	dir = filepath.Clean(dir)
+	name := strings.TrimSuffix(base, ".cfg")
+	exists := func(engine string) bool {
+		_, err := os.Stat(filepath.Join(dir, engine+".cfg"))
+		return err == nil
+	}
+	if c, ok := split(dir, name, exists); ok {
+		return c, nil
+	}
+	return Configuration{}, fmt.Errorf("no engine cfg file found for %s", file)
+}
+
+// split splits <engine>-<id> using the longest engine name for which
+// exists is true.
+func split(dir, name string, exists func(engine string) bool) (Configuration, bool) {
+	for i := len(name) - 1; i > 0; i-- {
+		if name[i] == '-' && exists(name[:i]) {
+			return Configuration{Dir: dir, Engine: name[:i], ID: name[i+1:]}, true
+		}
+	}
+	return Configuration{}, false
+}
@@ -0,0 +1,89 @@
+package cfg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	got := Parse("engine=go-chart\r\nconfig=pie\r\n\r\ntitle=a=b\nnot a setting\n")
+	want := Settings{{"engine", "go-chart"}, {"config", "pie"}, {"title", "a=b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %v, want %v", got, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	common := Settings{{"engine", "e"}, {"title", ""}, {"legend", "false"}}
+	config := Settings{{"config", "pie"}, {"legend", "true"}}
+	got := common.Merge(config)
+	want := Settings{{"engine", "e"}, {"title", ""}, {"legend", "true"}, {"config", "pie"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() = %v, want %v", got, want)
+	}
+	if v, ok := got.Get("legend"); !ok || v != "true" {
+		t.Errorf("Get(legend) = %q, %v", v, ok)
+	}
+	if s := got.String(); s != "engine=e\ntitle=\nlegend=true\nconfig=pie\n" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"go.cfg", "go-x.cfg", "go-chart.cfg", "go-chart-pie.cfg", "other-y.cfg"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("name="+name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configs, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Configuration{
+		{dir, "go", "chart"},
+		{dir, "go-chart", "pie"},
+		{dir, "go", "x"},
+	}
+	if !reflect.DeepEqual(configs, want) {
+		t.Errorf("List() = %v, want %v", configs, want)
+	}
+
+	if _, err := List(t.TempDir()); err == nil {
+		t.Error("List() of empty folder succeeded")
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"go.cfg", "go-chart.cfg", "go-chart-pie.cfg", "other-y.cfg"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("name="+name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := Find(filepath.Join(dir, "go-chart-pie.cfg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Configuration{dir, "go-chart", "pie"}); c != want {
+		t.Errorf("Find() = %v, want %v", c, want)
+	}
+
+	if _, err := Find(filepath.Join(dir, "other-y.cfg")); err == nil {
+		t.Error("Find() of cfg file without engine cfg file succeeded")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	settings, err := Load("../../example/go-chart/config", "go-chart", "pie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"engine": "go-chart", "config": "pie", "legend": "false"} {
+		if v, _ := settings.Get(name); v != want {
+			t.Errorf("%s = %q, want %q", name, v, want)
+		}
+	}
+}
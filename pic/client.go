@@ -1,15 +1,118 @@
 package pic
 
+import (
+	"sync"
+	"sync/atomic"
+)
+
 // Client is responsible for creating the chart Builder.
 type Client interface {
 	NewBuilder(c *Config) Builder
 }
 
-// SetClient specifies the implementation.
+// SetClient specifies the implementation. The Client is used for every
+// chart unless engines have been registered with Register, in which case it
+// is used only for configurations without an engine property. Any overrides
+// described by OptionsEnvVar are merged over the options.
 func SetClient(c Client, o Options) {
-	client = c
-	options = o
+	o, used, errs := overrideOptions(o)
+	update(func(s *state) {
+		s.client = c
+		s.options = o
+	})
+	applyOptions(o)
+	logOverrides(used, errs)
+}
+
+// SetOptions specifies the options without changing the implementation.
+// Use SetOptions with Register when hosting more than one chart engine.
+// Any overrides described by OptionsEnvVar are merged over the options.
+func SetOptions(o Options) {
+	o, used, errs := overrideOptions(o)
+	update(func(s *state) {
+		s.options = o
+	})
+	applyOptions(o)
+	logOverrides(used, errs)
+}
+
+// applyOptions configures the process-wide services used by all charts.
+func applyOptions(o Options) {
 	initLogger(o)
+	fonts.setLimit(o.FontCacheSize)
+	startMetrics(o)
+}
+
+// Register specifies the implementation of a chart engine. The engineID
+// is matched against the engine property of the chart configuration, which
+// is the id attribute of the propertyTemplate element in the engine's xml
+// file. Registering a nil Client removes the engine.
+func Register(engineID string, c Client) {
+	update(func(s *state) {
+		if c == nil {
+			delete(s.clients, engineID)
+		} else {
+			s.clients[engineID] = c
+		}
+	})
 }
 
-var client Client
+// state holds the implementation and options. It is never modified once
+// published, so that concurrent calls to EnchCreateImage each see a
+// consistent snapshot while SetClient, SetOptions or Register swap in a
+// new one.
+type state struct {
+	client   Client
+	clients  map[string]Client
+	options  Options
+	override Client // Used for every engine, as passed to CreateImage.
+}
+
+var (
+	stateMu sync.Mutex   // Serialises updates to current.
+	current atomic.Value // Holds *state.
+)
+
+// load gets the current implementation and options.
+func load() *state {
+	if s, ok := current.Load().(*state); ok {
+		return s
+	}
+	return &state{}
+}
+
+// update publishes a modified copy of the current state.
+func update(f func(s *state)) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	old := load()
+	s := &state{
+		client:  old.client,
+		clients: make(map[string]Client, len(old.clients)),
+		options: old.options,
+	}
+	for id, c := range old.clients {
+		s.clients[id] = c
+	}
+	f(s)
+	current.Store(s)
+}
+
+// lookupClient finds the implementation of a chart engine. The Client
+// specified by SetClient is used when no engines are registered, as in a
+// library hosting a single engine, or when no engine is named. Once engines
+// are registered, an engine that is not among them has no implementation,
+// so that a library hosting several engines never renders with the wrong
+// one.
+func (s *state) lookupClient(engineID string) Client {
+	if s.override != nil {
+		return s.override
+	}
+	if c, ok := s.clients[engineID]; ok {
+		return c
+	}
+	if engineID != "" && len(s.clients) > 0 {
+		return nil
+	}
+	return s.client
+}
@@ -46,6 +46,24 @@ func TestSetClient(t *testing.T) {
 		},
 	)
 
-	assertEqual(t, mc, client)
-	assertEqual(t, options.LogInfo(), true)
+	assertEqual(t, mc, load().client)
+	assertEqual(t, load().options.LogInfo(), true)
+}
+
+func TestRegister(t *testing.T) {
+	mc := mockClient{}
+	restore := withClient(nil, Options{})
+	Register("mock", mc)
+	assertEqual(t, load().lookupClient("mock"), Client(mc))
+	Register("mock", nil)
+	assertEqual(t, load().lookupClient("mock"), nil)
+	restore()
+
+	defer withClient(mc, Options{})()
+	assertEqual(t, load().lookupClient(""), Client(mc))
+	assertEqual(t, load().lookupClient("mock"), Client(mc))
+	Register("other", mockClient{})
+	defer Register("other", nil)
+	assertEqual(t, load().lookupClient(""), Client(mc))
+	assertEqual(t, load().lookupClient("mock"), nil)
 }
@@ -17,15 +17,35 @@ var DefaultColor = Color{K: 100}
 func (c *Color) parse(v []Value) error {
 	if len(v) == 1 {
 		// The value just contains the named colour index.
-		c.parseNamedColor(v[0])
+		index, err := c.parseAttribute(v, 0)
+		if err != nil {
+			return err
+		}
+		if index < 0 || int(index) >= len(namedRgbColors) {
+			index = 0
+		}
+		c.R, c.G, c.B = c.namedRgb(index)
+		c.C, c.M, c.Y, c.K = c.namedCmyk(index)
 		return nil
 	}
 	if len(v) == 4 {
-		// Not interested in the first two attributes:
+		// Not interested in the first two attributes, but they must be valid:
 		// - Master colour type (Named, RGB or CMYK).
 		// - Named colour index.
-		rgb := c.parseAttribute(v[2])
-		cmyk := c.parseAttribute(v[3])
+		var attrs [4]int32
+		for i := range attrs {
+			var err error
+			if attrs[i], err = c.parseAttribute(v, i); err != nil {
+				return err
+			}
+		}
+		rgb, cmyk := attrs[2], attrs[3]
+		if rgb < 0 || rgb > 0xffffff {
+			return fmt.Errorf("invalid RGB value %d in color set %v", rgb, v)
+		}
+		if cmyk < 0 {
+			return fmt.Errorf("invalid CMYK value %d in color set %v", cmyk, v)
+		}
 		c.R = uint8((rgb >> 16) & 0xff)
 		c.G = uint8((rgb >> 8) & 0xff)
 		c.B = uint8(rgb & 0xff)
@@ -38,21 +58,12 @@ func (c *Color) parse(v []Value) error {
 	return fmt.Errorf("invalid color set %v", v)
 }
 
-func (c *Color) parseNamedColor(v Value) {
-	index := c.parseAttribute(v)
-	if index > 15 {
-		index = 0
-	}
-	c.R, c.G, c.B = c.namedRgb(index)
-	c.C, c.M, c.Y, c.K = c.namedCmyk(index)
-}
-
-func (Color) parseAttribute(v Value) int32 {
-	i, err := strconv.ParseInt(string(v), 10, 32)
+func (Color) parseAttribute(v []Value, i int) (int32, error) {
+	n, err := strconv.ParseInt(string(v[i]), 10, 32)
 	if err != nil {
-		i = 0
+		return 0, fmt.Errorf("invalid color attribute '%s' in color set %v", v[i], v)
 	}
-	return int32(i)
+	return int32(n), nil
 }
 
 var namedRgbColors = [][]uint8{
@@ -76,7 +87,7 @@ var namedRgbColors = [][]uint8{
 
 func (Color) namedRgb(index int32) (r, g, b uint8) {
 	rgb := namedRgbColors[index]
-	r, b, g = rgb[0], rgb[1], rgb[2]
+	r, g, b = rgb[0], rgb[1], rgb[2]
 	return
 }
 
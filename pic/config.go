@@ -1,31 +1,61 @@
 package pic
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Config represents the configuration of the chart to be rendered.
+// It holds all of the state of a single call to EnchCreateImage, and is
+// safe for concurrent use by the Builder.
 type Config struct {
-	resolver
+	resolver            Resolver
 	properties, symbols map[string]string
-	fontResources       map[GUID]*FontResource
-	fontStyles          map[GUID]*FontStyle
+	log                 *slog.Logger
+	redact              *redactor
+
+	mu            sync.Mutex // Guards the fields below.
+	fontResources map[GUID]*FontResource
+	fontStyles    map[GUID]*FontStyle
+	fontErrors    map[GUID]error
+	styleErrors   map[GUID]error
+	err           error
+}
+
+// NewConfig creates the configuration of a chart from properties and symbols
+// in the form passed to EnchCreateImage, with the resolver answering the
+// questions otherwise asked of Designer/Generate. It allows a Builder to be
+// used without Designer/Generate, for example in tests; see package pictest.
+func NewConfig(r Resolver, props, syms string) *Config {
+	return newConfig(r, props, syms)
 }
 
-func newConfig(r resolver, props, syms string) *Config {
+func newConfig(r Resolver, props, syms string) *Config {
 	return &Config{
 		resolver:      r,
 		properties:    loadSettings(props, '\n'),
 		symbols:       loadSettings(syms, '\n'),
+		log:           rootLogger(),
 		fontResources: make(map[GUID]*FontResource),
 		fontStyles:    make(map[GUID]*FontStyle),
+		fontErrors:    make(map[GUID]error),
+		styleErrors:   make(map[GUID]error),
 	}
 }
 
 // NumberFormat defines how a number should be formatted for display.
 func (c *Config) NumberFormat() NumberFormat {
-	return c.resolver.numberFormat()
+	return c.resolver.NumberFormat()
+}
+
+// DateTimeFormat defines how dates and times should be formatted for display.
+func (c *Config) DateTimeFormat() DateTimeFormat {
+	return c.resolver.DateTimeFormat()
 }
 
 // Value gets the value of a property from the configuration.
@@ -39,33 +69,67 @@ func (c *Config) Value(name string) Value {
 // Integer gets the value of a property as an integer. Zero is returned
 // if the string is empty or the conversion fails.
 func (c *Config) Integer(name string) int32 {
-	return c.ResolveInteger(c.Value(name))
+	i, err := c.IntegerE(name)
+	c.fail(err)
+	return i
+}
+
+// IntegerE gets the value of a property as an integer. Zero is returned
+// if the string is empty.
+func (c *Config) IntegerE(name string) (int32, error) {
+	i, err := c.ResolveIntegerE(c.Value(name))
+	return i, propertyError(err, name)
 }
 
 // Twiplet gets the value of a property as a Twiplet. Zero is returned
 // if the string is empty or the conversion fails.
 func (c *Config) Twiplet(name string) Twiplet {
-	return c.ResolveTwiplet(c.Value(name))
+	t, err := c.TwipletE(name)
+	c.fail(err)
+	return t
+}
+
+// TwipletE gets the value of a property as a Twiplet. Zero is returned
+// if the string is empty.
+func (c *Config) TwipletE(name string) (Twiplet, error) {
+	t, err := c.ResolveTwipletE(c.Value(name))
+	return t, propertyError(err, name)
 }
 
 // Number gets the value of a property as a number. Zero is returned
 // if the string is empty or the conversion fails.
 func (c *Config) Number(name string) float64 {
-	return c.ResolveNumber(c.Value(name))
+	n, err := c.NumberE(name)
+	c.fail(err)
+	return n
+}
+
+// NumberE gets the value of a property as a number. Zero is returned
+// if the string is empty.
+func (c *Config) NumberE(name string) (float64, error) {
+	n, err := c.ResolveNumberE(c.Value(name))
+	return n, propertyError(err, name)
 }
 
 // ResolveInteger converts a value to an integer. Zero is returned
 // if the string is empty or the conversion fails.
 func (c *Config) ResolveInteger(v Value) int32 {
+	i, err := c.ResolveIntegerE(v)
+	c.fail(err)
+	return i
+}
+
+// ResolveIntegerE converts a value to an integer. Zero is returned
+// if the string is empty.
+func (c *Config) ResolveIntegerE(v Value) (int32, error) {
 	if v == "" {
-		return 0
+		return 0, nil
 	}
-	i, err := c.resolver.integer(string(v))
+	i, err := c.resolver.Integer(string(v))
 	if err != nil {
-		log.Println(err)
-		return 0
+		return 0, &Error{Code: InvalidDataString, Err: err}
 	}
-	return i
+	return i, nil
 }
 
 // ResolveTwiplet converts a value to a Twiplet. Zero is returned
@@ -74,46 +138,192 @@ func (c *Config) ResolveTwiplet(v Value) Twiplet {
 	return Twiplet(c.ResolveInteger(v))
 }
 
+// ResolveTwipletE converts a value to a Twiplet. Zero is returned
+// if the string is empty.
+func (c *Config) ResolveTwipletE(v Value) (Twiplet, error) {
+	i, err := c.ResolveIntegerE(v)
+	return Twiplet(i), err
+}
+
 // ResolveNumber converts a value to a number. Zero is returned
 // if the string is empty or the conversion fails.
 func (c *Config) ResolveNumber(v Value) float64 {
+	n, err := c.ResolveNumberE(v)
+	c.fail(err)
+	return n
+}
+
+// ResolveNumberE converts a value to a number. Zero is returned
+// if the string is empty.
+func (c *Config) ResolveNumberE(v Value) (float64, error) {
 	if v == "" {
-		return 0
+		return 0, nil
 	}
-	n, err := c.resolver.number(string(v))
+	n, err := c.resolver.Number(string(v))
 	if err != nil {
-		log.Println(err)
-		return 0
+		return 0, &Error{Code: InvalidDataString, Err: err}
 	}
-	return n
+	return n, nil
+}
+
+// Date gets the value of a property as a date. The zero time.Time is
+// returned if the string is empty or the conversion fails.
+func (c *Config) Date(name string) time.Time {
+	d, err := c.DateE(name)
+	c.fail(err)
+	return d
+}
+
+// DateE gets the value of a property as a date. The zero time.Time is
+// returned if the string is empty.
+func (c *Config) DateE(name string) (time.Time, error) {
+	d, err := c.ResolveDateE(c.Value(name))
+	return d, propertyError(err, name)
+}
+
+// Time gets the value of a property as a time of day. The zero time.Time
+// is returned if the string is empty or the conversion fails.
+func (c *Config) Time(name string) time.Time {
+	t, err := c.TimeE(name)
+	c.fail(err)
+	return t
+}
+
+// TimeE gets the value of a property as a time of day. The zero time.Time
+// is returned if the string is empty.
+func (c *Config) TimeE(name string) (time.Time, error) {
+	t, err := c.ResolveTimeE(c.Value(name))
+	return t, propertyError(err, name)
+}
+
+// ResolveDate converts a value to a date at midnight UTC. The zero
+// time.Time is returned if the string is empty or the conversion fails.
+func (c *Config) ResolveDate(v Value) time.Time {
+	d, err := c.ResolveDateE(v)
+	c.fail(err)
+	return d
+}
+
+// ResolveDateE converts a value to a date at midnight UTC. The zero
+// time.Time is returned if the string is empty.
+func (c *Config) ResolveDateE(v Value) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	d, err := c.resolver.Date(string(v))
+	if err != nil {
+		return time.Time{}, &Error{Code: InvalidDataString, Err: err}
+	}
+	return d, nil
+}
+
+// ResolveTime converts a value to a time of day on 1st January of year 0
+// UTC. The zero time.Time is returned if the string is empty or the
+// conversion fails.
+func (c *Config) ResolveTime(v Value) time.Time {
+	t, err := c.ResolveTimeE(v)
+	c.fail(err)
+	return t
+}
+
+// ResolveTimeE converts a value to a time of day on 1st January of year 0
+// UTC. The zero time.Time is returned if the string is empty.
+func (c *Config) ResolveTimeE(v Value) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := c.resolver.TimeOfDay(string(v))
+	if err != nil {
+		return time.Time{}, &Error{Code: InvalidDataString, Err: err}
+	}
+	return t, nil
+}
+
+// Resolve converts a value to a Datum using the value's own data type.
+// The Type of the resulting Datum is the type determined by
+// Designer/Generate, which may differ from the type of the value.
+// NotSet is returned if the string is empty.
+func (c *Config) Resolve(v Value) (Datum, error) {
+	if v == "" {
+		return Datum{Type: NotSet}, nil
+	}
+	t := v.Type()
+	if t == NotSet {
+		err := fmt.Errorf("unrecognised value type '%s'", v)
+		return Datum{Type: NotSet}, &Error{Code: InvalidDataString, Err: err}
+	}
+	d, err := c.resolver.DataValue(string(v), t)
+	if err != nil {
+		return Datum{Type: NotSet}, &Error{Code: InvalidDataString, Err: err}
+	}
+	return d, nil
 }
 
-// Color gets the value of a property as a Color.
+// Color gets the value of a property as a Color. DefaultColor is returned
+// if the string is empty or the conversion fails.
 func (c *Config) Color(name string) Color {
+	color, err := c.ColorE(name)
+	c.fail(err)
+	return color
+}
+
+// ColorE gets the value of a property as a Color. DefaultColor is returned
+// if the string is empty.
+func (c *Config) ColorE(name string) (Color, error) {
 	if val, ok := c.properties[name]; ok && val != "" {
-		return c.loadColor(val)
+		color, err := c.loadColorE(val)
+		return color, propertyError(err, name)
 	}
-	return DefaultColor
+	return DefaultColor, nil
 }
 
-// Font gets the value of a property as a Font.
+// Font gets the value of a property as a Font. DefaultFont is returned
+// if the string is empty or the conversion fails.
 func (c *Config) Font(name string) Font {
+	f, err := c.FontE(name)
+	c.fail(err)
+	return f
+}
+
+// FontE gets the value of a property as a Font. DefaultFont is returned
+// if the string is empty.
+func (c *Config) FontE(name string) (Font, error) {
 	if val, ok := c.properties[name]; ok && val != "" {
-		return c.loadFont(val)
+		f, err := c.loadFontE(val)
+		return f, propertyError(err, name)
 	}
-	return DefaultFont
+	return DefaultFont, nil
 }
 
-// ResolveFont gets a font resource from a Font value.
+// ResolveFont gets a font resource from a Font value. An empty font
+// resource is returned if the font cannot be resolved.
 func (c *Config) ResolveFont(f Font) *FontStyle {
+	fs, err := c.ResolveFontE(f)
+	c.fail(err)
+	return fs
+}
+
+// ResolveFontE gets a font resource from a Font value. The error is set
+// if the font cannot be resolved, or its TrueType font cannot be loaded,
+// in which case the font resource is still returned but may be empty.
+func (c *Config) ResolveFontE(f Font) (*FontStyle, error) {
+	var fs *FontStyle
+	var err error
 	if f.IsStyle {
-		return c.resolveFontStyle(f)
+		fs, err = c.resolveFontStyle(f)
+	} else {
+		var fr *FontResource
+		fr, err = c.resolveFontResource(f)
+		fs = &FontStyle{
+			FontResource: fr,
+			Color:        f.Color,
+			Underline:    f.Underline,
+		}
 	}
-	return &FontStyle{
-		FontResource: c.resolveFontResource(f),
-		Color:        f.Color,
-		Underline:    f.Underline,
+	if err != nil {
+		return fs, &Error{Code: UnresolvedFont, Err: err}
 	}
+	return fs, nil
 }
 
 // Dataset gets a set of data values from the configuration.
@@ -129,6 +339,11 @@ func (c *Config) Name() string {
 	return string(c.Value("config"))
 }
 
+// Engine gets the chart engine ID.
+func (c *Config) Engine() string {
+	return string(c.Value("engine"))
+}
+
 // Data gets all of the data properties from the configuration.
 func (c *Config) Data() *Data {
 	return &Data{
@@ -178,7 +393,7 @@ func (c *Config) DataColors() (colors []Color) {
 
 // DataStyles gets the data styles dataset from the configuration.
 func (c *Config) DataStyles() DataStyles {
-	return c.loadDataStyles(c.Dataset("data.styles"))
+	return c.loadDataStyles("data.styles")
 }
 
 // DataLabels gets the data labels dataset from the configuration.
@@ -199,12 +414,12 @@ func (c *Config) DataFonts() (fonts []Font) {
 
 // DataFormats gets the data formats dataset from the configuration.
 func (c *Config) DataFormats() DataStyles {
-	return c.loadDataStyles(c.Dataset("data.formats"))
+	return c.loadDataStyles("data.formats")
 }
 
 func loadSettings(input string, sep byte) map[string]string {
 	out := make(map[string]string)
-	for _, line := range strings.Split(input, string(sep)) {
+	for _, line := range strings.Split(input, string([]byte{sep})) {
 		line = strings.Trim(line, "\r")
 		setting := strings.SplitN(line, "=", 2)
 		if len(setting) == 2 {
@@ -252,26 +467,40 @@ func (c *Config) loadDataset(input string) (ds Dataset) {
 	return
 }
 
-func (c *Config) loadDataStyles(ds Dataset) (styles DataStyles) {
+func (c *Config) loadDataStyles(name string) (styles DataStyles) {
+	ds := c.Dataset(name)
 	styles = make(DataStyles, len(ds))
 	for i, set := range ds {
 		styles[i] = make([]DataStyle, len(set))
 		for j, val := range set {
-			styles[i][j] = c.loadDataStyle(val)
+			var err error
+			if styles[i][j], err = c.loadDataStyle(val); err != nil {
+				c.fail(&Error{Code: InvalidValue, Property: name, Err: err})
+			}
 		}
 	}
 	return
 }
 
-func (c *Config) loadDataStyle(v Value) (style DataStyle) {
+// loadDataStyle loads a data style in the form type:<sep>name=value... where
+// <sep> is the character separating the settings, such as '+'. An empty
+// value is an empty data style.
+func (c *Config) loadDataStyle(v Value) (style DataStyle, err error) {
+	if v == "" {
+		return
+	}
 	options := strings.SplitN(string(v), ":", 2)
 	if len(options) != 2 {
-		return
+		return DataStyle{}, fmt.Errorf("invalid data style '%s'", v)
 	}
 	style.Type = options[0]
 	style.Settings = make(map[string]Value)
 	if len(options[1]) > 0 {
-		settings := loadSettings(options[1][1:], options[1][0])
+		sep := options[1][0]
+		if !isSeparator(sep) {
+			return DataStyle{}, fmt.Errorf("invalid data style separator %q in '%s'", options[1][:1], v)
+		}
+		settings := loadSettings(options[1][1:], sep)
 		for k, s := range settings {
 			style.Settings[k] = Value(c.lookupSymbol(s))
 		}
@@ -279,57 +508,131 @@ func (c *Config) loadDataStyle(v Value) (style DataStyle) {
 	return
 }
 
-func (c *Config) loadColor(val string) (color Color) {
+// isSeparator determines whether a byte can separate the settings of a data
+// style: an ASCII control or punctuation character other than '='.
+func isSeparator(b byte) bool {
+	switch {
+	case b >= utf8.RuneSelf, b == '=', b == ' ':
+		return false
+	case b < ' ', b == 0x7f:
+		return true
+	}
+	return unicode.IsPunct(rune(b)) || unicode.IsSymbol(rune(b))
+}
+
+func (c *Config) loadColor(val string) Color {
+	color, err := c.loadColorE(val)
+	c.fail(err)
+	return color
+}
+
+func (c *Config) loadColorE(val string) (color Color, err error) {
+	if val == "" {
+		return DefaultColor, nil
+	}
 	// A colour value is represented by a single-row dataset.
 	ds := c.loadDataset(val)
-	if err := color.parse(ds[0]); err != nil {
-		log.Println(err)
-		return DefaultColor
+	if err = color.parse(ds[0]); err != nil {
+		return DefaultColor, &Error{Code: InvalidValue, Err: err}
 	}
 	return
 }
 
-func (c *Config) loadFont(val string) (f Font) {
+func (c *Config) loadFont(val string) Font {
+	f, err := c.loadFontE(val)
+	c.fail(err)
+	return f
+}
+
+func (c *Config) loadFontE(val string) (f Font, err error) {
+	if val == "" {
+		return DefaultFont, nil
+	}
 	// A font value is represented by a multi-row dataset.
 	ds := c.loadDataset(val)
-	if err := f.parse(ds); err != nil {
-		log.Println(err)
-		return DefaultFont
+	if err = f.parse(ds); err != nil {
+		return DefaultFont, &Error{Code: InvalidValue, Err: err}
 	}
 	return
 }
 
-func (c *Config) resolveFontResource(f Font) *FontResource {
+func (c *Config) resolveFontResource(f Font) (*FontResource, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	fr, ok := c.fontResources[f.GUID]
 	if ok {
-		return fr
+		return fr, c.fontErrors[f.GUID]
 	}
 	var err error
-	if fr, err = c.resolver.fontResource(f.GUID); err != nil {
-		log.Println(err)
+	if fr, err = c.resolver.FontResource(f.GUID); err != nil {
 		fr = &FontResource{}
-	} else if err = fr.loadTruetype(); err != nil {
-		log.Println(err)
+	} else {
+		err = fr.loadTruetype()
 	}
 	c.fontResources[f.GUID] = fr
-	return fr
+	if err != nil {
+		c.fontErrors[f.GUID] = err
+	}
+	c.logFont("Resolved font resource", f.GUID, fr, err)
+	return fr, err
 }
 
-func (c *Config) resolveFontStyle(f Font) *FontStyle {
+func (c *Config) resolveFontStyle(f Font) (*FontStyle, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	fs, ok := c.fontStyles[f.GUID]
 	if ok {
-		return fs
+		return fs, c.styleErrors[f.GUID]
 	}
 	var err error
-	if fs, err = c.resolver.fontStyle(f.GUID); err != nil {
-		log.Println(err)
+	if fs, err = c.resolver.FontStyle(f.GUID); err != nil {
 		fs = &FontStyle{
 			FontResource: &FontResource{},
 			Color:        DefaultColor,
 		}
-	} else if err = fs.FontResource.loadTruetype(); err != nil {
-		log.Println(err)
+	} else {
+		err = fs.FontResource.loadTruetype()
 	}
 	c.fontStyles[f.GUID] = fs
-	return fs
+	if err != nil {
+		c.styleErrors[f.GUID] = err
+	}
+	c.logFont("Resolved font style", f.GUID, fs.FontResource, err)
+	return fs, err
+}
+
+func (c *Config) logFont(msg string, guid GUID, fr *FontResource, err error) {
+	if err != nil {
+		c.log.Debug(msg, slog.String("guid", guid.String()), slog.Any("error", err))
+		return
+	}
+	c.log.Debug(
+		msg,
+		slog.String("guid", guid.String()),
+		slog.String("typeface", fr.Typeface),
+		slog.Float64("size", fr.PointSize),
+		slog.String("file", fr.Filename),
+	)
+}
+
+// fail logs a conversion failure and records the first one so that
+// EnchCreateImage can report it in strict mode.
+func (c *Config) fail(err error) {
+	if err == nil {
+		return
+	}
+	c.log.Error("Conversion failed", slog.String("error", c.redact.error(err)))
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = err
+	}
+	c.mu.Unlock()
+}
+
+// Err gets the first failure of the methods that do not return an error,
+// such as Integer or Font, which EnchCreateImage returns in strict mode.
+func (c *Config) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
 }
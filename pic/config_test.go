@@ -6,7 +6,9 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 type mockCallback struct {
@@ -21,23 +23,73 @@ func newMockCallback() *mockCallback {
 	}
 }
 
-func (mockCallback) integer(s string) (int32, error) {
+func (mockCallback) Integer(s string) (int32, error) {
 	i, err := strconv.ParseInt(s, 10, 32)
 	return int32(i), err
 }
 
-func (mockCallback) number(s string) (float64, error) {
+func (mockCallback) Number(s string) (float64, error) {
 	return strconv.ParseFloat(s, 64)
 }
 
-func (mockCallback) numberFormat() NumberFormat {
+func (mockCallback) Date(s string) (time.Time, error) {
+	d, err := time.Parse("2/1/2006", Value(s).Text())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return newDate(d.Year(), int(d.Month()), d.Day()), nil
+}
+
+func (mockCallback) TimeOfDay(s string) (time.Time, error) {
+	t, err := time.Parse("15:04:05", Value(s).Text())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return newTime(t.Hour(), t.Minute(), t.Second()), nil
+}
+
+func (mc mockCallback) DataValue(s string, t DataType) (Datum, error) {
+	var err error
+	d := Datum{Type: t}
+	switch t {
+	case Integer:
+		d.Integer, err = mc.Integer(Value(s).Text())
+	case Number:
+		d.Number, err = mc.Number(Value(s).Text())
+	case Currency:
+		d.Number, err = mc.Number(strings.TrimLeft(Value(s).Text(), "$£€"))
+	case Date:
+		d.Time, err = mc.Date(s)
+	case Time:
+		d.Time, err = mc.TimeOfDay(s)
+	}
+	return d, err
+}
+
+func (mockCallback) NumberFormat() NumberFormat {
 	return NumberFormat{
 		ThousandsSeparator: ',',
 		DecimalPoint:       '.',
 	}
 }
 
-func (mc *mockCallback) fontResource(guid GUID) (*FontResource, error) {
+func (mockCallback) DateTimeFormat() DateTimeFormat {
+	return DateTimeFormat{
+		MonthNames: []string{
+			"janvier", "février", "mars", "avril", "mai", "juin", "juillet",
+			"août", "septembre", "octobre", "novembre", "décembre",
+		},
+		WeekDayNames: []string{
+			"dimanche", "lundi", "mardi", "mercredi",
+			"jeudi", "vendredi", "samedi",
+		},
+		ShortDateFormat: "dd/MM/yyyy",
+		LongDateFormat:  "dddd d MMMM yyyy",
+		TimeFormat:      "HH:mm:ss",
+	}
+}
+
+func (mc *mockCallback) FontResource(guid GUID) (*FontResource, error) {
 	if v, ok := mc.fontResources[guid]; ok {
 		mc.fontResources[guid] = v + 1
 	} else {
@@ -50,7 +102,7 @@ func (mc *mockCallback) fontResource(guid GUID) (*FontResource, error) {
 	return fr, nil
 }
 
-func (mc *mockCallback) fontStyle(guid GUID) (*FontStyle, error) {
+func (mc *mockCallback) FontStyle(guid GUID) (*FontStyle, error) {
 	if v, ok := mc.fontStyles[guid]; ok {
 		mc.fontStyles[guid] = v + 1
 	} else {
@@ -117,6 +169,66 @@ func TestNumberValue(t *testing.T) {
 	assertEqual(t, c.Number("missing"), 0.0)
 }
 
+func TestDateValue(t *testing.T) {
+	p := fmt.Sprintf("date=%cd31/12/1999\ninvalid=foo", ascESC)
+	c := newConfig(newMockCallback(), p, "")
+	d := c.Date("date")
+	assertEqual(t, d.Year(), 1999)
+	assertEqual(t, d.Month(), time.December)
+	assertEqual(t, d.Day(), 31)
+	assertEqual(t, d.Hour(), 0)
+	assertEqual(t, c.Date("invalid").IsZero(), true)
+	assertEqual(t, c.Date("missing").IsZero(), true)
+}
+
+func TestTimeValue(t *testing.T) {
+	p := fmt.Sprintf("time=%[1]ct12:34:56\nmidnight=%[1]ct00:00:00\ninvalid=foo", ascESC)
+	c := newConfig(newMockCallback(), p, "")
+	tm := c.Time("time")
+	assertEqual(t, tm.Hour(), 12)
+	assertEqual(t, tm.Minute(), 34)
+	assertEqual(t, tm.Second(), 56)
+	assertEqual(t, c.Time("midnight").IsZero(), false)
+	assertEqual(t, c.Time("invalid").IsZero(), true)
+	assertEqual(t, c.Time("missing").IsZero(), true)
+}
+
+func TestResolve(t *testing.T) {
+	p := fmt.Sprintf("int=%[1]ci42\nnum=%[1]cn3.14\ndate=%[1]cd31/12/1999\n"+
+		"time=%[1]ct12:34:56\ncurr=%[1]c$£9.99\nstr=txt\nfoo=%[1]c?bar\nbad=%[1]cnfoo", ascESC)
+	c := newConfig(newMockCallback(), p, "")
+	d, err := c.Resolve(c.Value("int"))
+	assertEqual(t, err, nil)
+	assertEqual(t, d.Type, Integer)
+	assertEqual(t, d.Integer, int32(42))
+	d, err = c.Resolve(c.Value("num"))
+	assertEqual(t, err, nil)
+	assertEqual(t, d.Type, Number)
+	assertEqual(t, d.Number, 3.14)
+	d, err = c.Resolve(c.Value("curr"))
+	assertEqual(t, err, nil)
+	assertEqual(t, d.Type, Currency)
+	assertEqual(t, d.Number, 9.99)
+	d, err = c.Resolve(c.Value("date"))
+	assertEqual(t, err, nil)
+	assertEqual(t, d.Type, Date)
+	assertEqual(t, d.Time.Year(), 1999)
+	d, err = c.Resolve(c.Value("time"))
+	assertEqual(t, err, nil)
+	assertEqual(t, d.Type, Time)
+	assertEqual(t, d.Time.Minute(), 34)
+	d, err = c.Resolve(c.Value("str"))
+	assertEqual(t, err, nil)
+	assertEqual(t, d.Type, Neutral)
+	d, err = c.Resolve(c.Value("missing"))
+	assertEqual(t, err, nil)
+	assertEqual(t, d.Type, NotSet)
+	_, err = c.Resolve(c.Value("foo"))
+	assertEqual(t, err != nil, true)
+	_, err = c.Resolve(c.Value("bad"))
+	assertEqual(t, err != nil, true)
+}
+
 func TestNumberFormat(t *testing.T) {
 	c := newConfig(newMockCallback(), "", "")
 	nf := c.NumberFormat()
@@ -124,6 +236,18 @@ func TestNumberFormat(t *testing.T) {
 	assertEqual(t, nf.DecimalPoint, '.')
 }
 
+func TestDateTimeFormat(t *testing.T) {
+	c := newConfig(newMockCallback(), "", "")
+	dtf := c.DateTimeFormat()
+	assertEqual(t, len(dtf.MonthNames), 12)
+	assertEqual(t, dtf.MonthNames[7], "août")
+	assertEqual(t, len(dtf.WeekDayNames), 7)
+	assertEqual(t, dtf.WeekDayNames[0], "dimanche")
+	assertEqual(t, dtf.AM, "")
+	assertEqual(t, dtf.ShortDateFormat, "dd/MM/yyyy")
+	assertEqual(t, dtf.TimeFormat, "HH:mm:ss")
+}
+
 func TestValueType(t *testing.T) {
 	p := fmt.Sprintf("int=%[1]ci42\nnum=%[1]cn3.14\ndate=%[1]cd31/12/1999\n"+
 		"time=%[1]ct12:34:56\ncurr=%[1]c$9.99\nstr=txt\nfoo=%[1]c?bar", ascESC)
@@ -137,6 +261,49 @@ func TestValueType(t *testing.T) {
 	assertEqual(t, c.Value("foo").Type(), NotSet)
 }
 
+func TestConversionErrors(t *testing.T) {
+	p := fmt.Sprintf("int=foo\nnum=bar\ndate=%[1]cd99/99/99\ntime=%[1]ctnoon\n"+
+		"color=1,2,3\nfont=%[1]cfbad\nok=42", ascESC)
+	c := newConfig(newMockCallback(), p, "")
+	_, err := c.IntegerE("int")
+	assertEqual(t, ErrorCode(err), InvalidDataString)
+	assertEqual(t, err.(*Error).Property, "int")
+	_, err = c.TwipletE("int")
+	assertEqual(t, err != nil, true)
+	_, err = c.NumberE("num")
+	assertEqual(t, err != nil, true)
+	_, err = c.DateE("date")
+	assertEqual(t, err != nil, true)
+	_, err = c.TimeE("time")
+	assertEqual(t, err != nil, true)
+	color, err := c.ColorE("color")
+	assertEqual(t, ErrorCode(err), InvalidValue)
+	assertEqual(t, err.(*Error).Property, "color")
+	assertEqual(t, color, DefaultColor)
+	f, err := c.FontE("font")
+	assertEqual(t, err != nil, true)
+	assertEqual(t, f, DefaultFont)
+	assertEqual(t, c.Err(), nil)
+
+	i, err := c.IntegerE("ok")
+	assertEqual(t, err, nil)
+	assertEqual(t, i, int32(42))
+	i, err = c.IntegerE("missing")
+	assertEqual(t, err, nil)
+	assertEqual(t, i, int32(0))
+	color, err = c.ColorE("missing")
+	assertEqual(t, err, nil)
+	assertEqual(t, color, DefaultColor)
+}
+
+func TestConversionFailure(t *testing.T) {
+	c := newConfig(newMockCallback(), "num=foo\ncolor=1,2,3", "")
+	c.Color("color")
+	c.Number("num")
+	assertEqual(t, ErrorCode(c.Err()), InvalidValue)
+	assertEqual(t, c.Err().Error(), "InvalidValue: property 'color': invalid color set [1 2 3]")
+}
+
 func TestColorValue(t *testing.T) {
 	c := newConfig(newMockCallback(), "color1=15\ncolor2=0,4,16711935,6553600\ninvalid1=foo\ninvalid2=99\ninvalid3=1,2,3", "")
 	color1 := c.Color("color1")
@@ -158,6 +325,53 @@ func TestColorValue(t *testing.T) {
 	assertEqual(t, missing.K, uint8(100))
 }
 
+func TestNamedColorValue(t *testing.T) {
+	// The RGB values follow namedRgbColors in order, so that blue is blue
+	// rather than green; the CMYK values always did.
+	c := newConfig(newMockCallback(), "blue=1\nbrown=2\ngreen=3\nlow=-1\nhigh=16", "")
+	assertEqual(t, c.Color("blue"), Color{B: 255, C: 100, M: 100})
+	assertEqual(t, c.Color("brown"), Color{R: 144, G: 48, M: 38, Y: 57, K: 43})
+	assertEqual(t, c.Color("green"), Color{G: 255, C: 100, Y: 100})
+	assertEqual(t, c.Color("low"), Color{K: 100})
+	assertEqual(t, c.Color("high"), Color{K: 100})
+	assertEqual(t, c.Err(), nil)
+}
+
+func TestColorErrors(t *testing.T) {
+	for _, test := range []struct{ val, err string }{
+		{"d0", "invalid color attribute 'd0' in color set [d0]"},
+		{"1,x,0,0", "invalid color attribute 'x' in color set [1 x 0 0]"},
+		{"1,0,16777216,0", "invalid RGB value 16777216 in color set [1 0 16777216 0]"},
+		{"2,0,0,-1", "invalid CMYK value -1 in color set [2 0 0 -1]"},
+		{"1,0,,0", "invalid color attribute '' in color set [1 0  0]"},
+	} {
+		c := newConfig(newMockCallback(), "color="+test.val, "")
+		color, err := c.ColorE("color")
+		assertEqual(t, color, DefaultColor)
+		assertEqual(t, err.Error(), "InvalidValue: property 'color': "+test.err)
+	}
+}
+
+func TestFontErrors(t *testing.T) {
+	guid := "CAFE000000000000000000000000F00D"
+	for _, test := range []struct{ val, err string }{
+		{"d8", "invalid font value 'd8'"},
+		{"\x1bf" + guid + ",x|0|0", "invalid font set [[\x1bf" + guid + " x] [0] [0]]"},
+		{"\x1bf" + guid + "|0", "invalid font set [[\x1bf" + guid + "] [0]]"},
+		{"\x1bf$" + guid + "|0", "invalid font set [[\x1bf$" + guid + "] [0]]"},
+		{"\x1bf" + guid + "|0|0,1", "invalid font underline [0 1]"},
+		{"\x1bf" + guid + "|0|yes", "invalid font underline 'yes'"},
+		{"\x1bf" + guid + "|red|0", "invalid color attribute 'red' in color set [red]"},
+		{"\x1bfCAFE|0|0", "invalid GUID format 'CAFE'"},
+		{"\x1bfCAFE000000000000000000000000é0D|0|0", "invalid GUID char \"\\xc3\" at offset 28 in 'CAFE000000000000000000000000é0D'"},
+	} {
+		c := newConfig(newMockCallback(), "font="+test.val, "")
+		f, err := c.FontE("font")
+		assertEqual(t, f, DefaultFont)
+		assertEqual(t, err.Error(), "InvalidValue: property 'font': "+test.err)
+	}
+}
+
 func TestFontValue(t *testing.T) {
 	p := fmt.Sprintf("font1=%cfCAFE000000000000000000000000F00D|0,0,0,100|0\nfont2=invalid", ascESC)
 	c := newConfig(newMockCallback(), p, "")
@@ -174,6 +388,14 @@ func TestFontValue(t *testing.T) {
 	assertEqual(t, missing.GUID.IsZero(), true)
 }
 
+func TestParseGUID(t *testing.T) {
+	guid, err := ParseGUID("cafe000000000000000000000000F00D")
+	assertEqual(t, err, nil)
+	assertEqual(t, guid, GUID{0xCA, 0xFE, 14: 0xF0, 15: 0x0D})
+	_, err = ParseGUID("CAFE")
+	assertEqual(t, err.Error(), "invalid GUID format 'CAFE'")
+}
+
 func TestResolveFont(t *testing.T) {
 	p := fmt.Sprintf("font=%cfCAFE000000000000000000000000F00D|0,0,0,100|0", ascESC)
 	c := newConfig(newMockCallback(), p, "")
@@ -238,6 +460,11 @@ func TestName(t *testing.T) {
 	assertEqual(t, c.Name(), "test")
 }
 
+func TestEngine(t *testing.T) {
+	c := newConfig(newMockCallback(), "engine=go-chart\nconfig=test", "")
+	assertEqual(t, c.Engine(), "go-chart")
+}
+
 func TestData(t *testing.T) {
 	p := `
 data.values=4,2,3,4|2,4,1,3|8,5,4,5
@@ -254,6 +481,7 @@ data.formats=
 	assertEqual(t, d.Values[0][1].Text(), "2")
 	assertEqual(t, d.Values[0][2].Text(), "3")
 	assertEqual(t, d.Values[0][3].Text(), "4")
+	assertEqual(t, c.Err(), nil)
 }
 
 func TestEmptyDataset(t *testing.T) {
@@ -356,6 +584,21 @@ func TestDataFormats(t *testing.T) {
 	assertEqual(t, ds.CustomFormat(0, 1).Text(), "{value}")
 }
 
+func TestDataStyleErrors(t *testing.T) {
+	for _, test := range []struct{ val, err string }{
+		{"line", "invalid data style 'line'"},
+		{"line:lineStyle=solid", "invalid data style separator \"l\" in 'line:lineStyle=solid'"},
+		{"line:=lineStyle=solid", "invalid data style separator \"=\" in 'line:=lineStyle=solid'"},
+		{"line:éstyle=solid", "invalid data style separator \"\\xc3\" in 'line:éstyle=solid'"},
+	} {
+		c := newConfig(newMockCallback(), "data.styles=line:+style=solid,"+test.val, "")
+		ds := c.DataStyles()
+		assertEqual(t, ds.Setting(0, 0, "style").Text(), "solid")
+		assertEqual(t, ds.At(0, 1).Type, "")
+		assertEqual(t, c.Err().Error(), "InvalidValue: property 'data.styles': "+test.err)
+	}
+}
+
 func TestSingleSeriesDataColors(t *testing.T) {
 	testDataColors(t, ascUS)
 }
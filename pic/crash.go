@@ -0,0 +1,122 @@
+package pic
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// panicError is the error reported when pic recovers from a panic. It
+// keeps the stack of the panicking goroutine for the crash report.
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("unexpected failure: %v", e.value)
+}
+
+// newPanicError creates the error for a panic. It must be called while
+// the deferred function that recovered is running, so that the stack is
+// that of the panic.
+func newPanicError(v interface{}) *panicError {
+	return &panicError{value: v, stack: debug.Stack()}
+}
+
+// recovered creates the error returned by EnchCreateImage for a panic.
+func recovered(v interface{}) *Error {
+	return &Error{Code: Failed, Err: newPanicError(v)}
+}
+
+var crashID atomic.Uint64
+
+// crashReport describes a panic recovered by pic.
+type crashReport struct {
+	function     string // The function or callback that panicked.
+	call         uint64 // The correlation ID of the call to EnchCreateImage.
+	config       string
+	engine       string
+	spec         *ImageSpec
+	props, syms  string
+	panic        *panicError
+	redact       *redactor
+	includeProps bool
+}
+
+// save writes the crash report to a timestamped file next to the log file,
+// and logs where it is.
+func (cr *crashReport) save(o Options, l *slog.Logger) {
+	cr.includeProps = o.CrashReportConfig
+	name := crashFileName(o, time.Now())
+	err := os.WriteFile(name, cr.bytes(), 0644)
+	if err != nil {
+		l.Error(
+			"Unexpected failure",
+			slog.String("function", cr.function),
+			slog.Any("panic", cr.redact.text(cr.panic.value)),
+			slog.String("stack", string(cr.panic.stack)),
+			slog.Any("error", err),
+		)
+		return
+	}
+	l.Error(
+		"Unexpected failure",
+		slog.String("function", cr.function),
+		slog.Any("panic", cr.redact.text(cr.panic.value)),
+		slog.String("crashReport", name),
+	)
+}
+
+func (cr *crashReport) bytes() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Time: %s\n", time.Now().Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "Process: %d\n", os.Getpid())
+	fmt.Fprintf(&b, "Go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "Function: %s\n", cr.function)
+	if cr.call != 0 {
+		fmt.Fprintf(&b, "Call: %d\n", cr.call)
+		fmt.Fprintf(&b, "Configuration: %s\n", cr.config)
+		fmt.Fprintf(&b, "Engine: %s\n", cr.engine)
+	}
+	if s := cr.spec; s != nil {
+		fmt.Fprintf(&b, "Image: width=%d, height=%d, DPI=%d, format=%v, colorspace=%v\n",
+			s.Width, s.Height, s.DPI, s.Format, s.ColorSpace)
+	}
+	fmt.Fprintf(&b, "Panic: %v\n\n%s", cr.redact.text(cr.panic.value), cr.panic.stack)
+	if cr.includeProps && cr.call != 0 {
+		fmt.Fprintf(&b, "\n[Properties]\n%s\n\n[Symbols]\n%s\n",
+			cr.redact.properties(cr.props), cr.redact.symbols(cr.syms))
+	}
+	return b.Bytes()
+}
+
+// crashFileName gets a unique name for a crash report in CrashDir, or in
+// the folder of the log file, or failing that the temporary folder. The
+// name starts with that of the log file, if any.
+func crashFileName(o Options, now time.Time) string {
+	dir, prefix := o.CrashDir, "pic"
+	logOutputMu.Lock()
+	if logOutput != nil {
+		if dir == "" {
+			dir = filepath.Dir(logOutput.name)
+		}
+		base := filepath.Base(logOutput.name)
+		prefix = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	logOutputMu.Unlock()
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, fmt.Sprintf(
+		"%s-crash-%s-%d-%d.txt",
+		prefix, now.Format("20060102-150405.000"), os.Getpid(), crashID.Add(1),
+	))
+}
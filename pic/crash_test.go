@@ -0,0 +1,120 @@
+package pic
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// panicCallback panics when asked to convert an integer.
+type panicCallback struct {
+	*mockCallback
+}
+
+func (panicCallback) Integer(s string) (int32, error) {
+	var m map[string]int32
+	m[s] = 1
+	return 0, nil
+}
+
+func readCrashReport(t *testing.T, dir string) string {
+	names, _ := filepath.Glob(filepath.Join(dir, "*-crash-*.txt"))
+	if len(names) != 1 {
+		t.Fatalf("Expected one crash report, found %d", len(names))
+	}
+	b, err := os.ReadFile(names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func assertContains(t *testing.T, s string, substrs ...string) {
+	t.Helper()
+	for _, sub := range substrs {
+		if !strings.Contains(s, sub) {
+			t.Errorf("Missing %q in:\n%s", sub, s)
+		}
+	}
+}
+
+func TestCrashReport(t *testing.T) {
+	for _, timeout := range []time.Duration{0, time.Minute} {
+		dir := t.TempDir()
+		defer withClient(nil, Options{CrashDir: dir, CrashReportConfig: true, RenderTimeout: timeout})()
+		Register("test", renderClient(func(c *Config) (*bytes.Buffer, error) {
+			panic("oops")
+		}))
+		defer Register("test", nil)
+
+		props := "config=pie\nengine=test\ntitle=secret"
+		assertEqual(t, createImage(newMockCallback(), props, "sym=value", testImage()), Failed)
+
+		report := readCrashReport(t, dir)
+		assertContains(t, report,
+			"Function: EnchCreateImage\n",
+			"Configuration: pie\n",
+			"Engine: test\n",
+			"Image: width=144000, height=144000, DPI=96, format=PNG, colorspace=RGB\n",
+			"Panic: oops\n",
+			"crash_test.go",
+			"title=secret",
+			"sym=value",
+		)
+	}
+}
+
+func TestCrashReportWithoutConfig(t *testing.T) {
+	dir := t.TempDir()
+	defer withClient(renderClient(func(c *Config) (*bytes.Buffer, error) {
+		panic("oops")
+	}), Options{CrashDir: dir})()
+
+	assertEqual(t, createImage(newMockCallback(), "title=secret", "", testImage()), Failed)
+	if report := readCrashReport(t, dir); strings.Contains(report, "secret") {
+		t.Errorf("Crash report includes the properties:\n%s", report)
+	}
+}
+
+func TestCallbackPanic(t *testing.T) {
+	dir := t.TempDir()
+	done := make(chan error)
+	defer withClient(renderClient(func(c *Config) (*bytes.Buffer, error) {
+		// A panic in a goroutine of the builder would end the process.
+		go func() {
+			_, err := c.IntegerE("size")
+			done <- err
+		}()
+		if err := <-done; err != nil {
+			return nil, err
+		}
+		return bytes.NewBufferString("chart"), nil
+	}), Options{CrashDir: dir})()
+
+	rc := createImage(panicCallback{newMockCallback()}, "size=42", "", testImage())
+	assertEqual(t, rc, InvalidDataString)
+	assertContains(t, readCrashReport(t, dir), "Function: callback Integer\n", "assignment to entry in nil map")
+}
+
+func TestDestroyImagePanic(t *testing.T) {
+	dir := t.TempDir()
+	defer withClient(nil, Options{CrashDir: dir})()
+	assertEqual(t, destroyImage(nil), Failed)
+	assertContains(t, readCrashReport(t, dir), "Function: EnchDestroyImage\n")
+}
+
+func TestCrashFileName(t *testing.T) {
+	defer closeLogOutput()
+	now := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+	dir := t.TempDir()
+	if err := openLogFile(Options{LogFileName: filepath.Join(dir, "go-chart.log")}); err != nil {
+		t.Fatal(err)
+	}
+	name := crashFileName(Options{}, now)
+	assertEqual(t, filepath.Dir(name), dir)
+	assertEqual(t, strings.HasPrefix(filepath.Base(name), "go-chart-crash-20200304-050607.000-"), true)
+	assertEqual(t, crashFileName(Options{}, now) != name, true)
+}
@@ -0,0 +1,145 @@
+package pic
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ImageSpec describes the chart image required by Designer/Generate. The
+// Builder may change the format and colour space to those it supports.
+type ImageSpec struct {
+	Width, Height Twiplet
+	DPI           int32
+	Format        ImageFormat
+	ColorSpace    ColorSpace
+}
+
+// CreateImage creates a chart image in the same way as EnchCreateImage, but
+// without Designer/Generate: the resolver answers the questions otherwise
+// asked of the host, and the image data is returned in Go memory. If client
+// is nil, the Client for the engine of the configuration is used, as for
+// EnchCreateImage. The logging options are ignored, as the log is set up by
+// SetClient or SetOptions. The ReturnCode that EnchCreateImage would return
+// can be found from the error with ErrorCode.
+func CreateImage(client Client, r Resolver, props, syms string, spec *ImageSpec, o Options) ([]byte, error) {
+	st := load()
+	if client != nil {
+		st = &state{override: client}
+	}
+	buf, err := st.createImage(r, props, syms, spec, o)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.free()
+	return buf.bytes(), nil
+}
+
+// createImage creates the chart image in memory allocated by C, logging the
+// outcome, and capturing the call if required.
+func (s *state) createImage(r Resolver, props, syms string, spec *ImageSpec, o Options) (buf *cBuffer, err error) {
+	start := time.Now()
+	id := callID.Add(1)
+
+	config := newConfig(r, props, syms)
+	l := newCallLogger(id, config.Name())
+	rd := o.Redact.redactor()
+	config.log = l
+	config.redact = rd
+	crash := func(function string, pe *panicError) {
+		cr := &crashReport{
+			function: function,
+			call:     id,
+			config:   config.Name(),
+			engine:   config.Engine(),
+			spec:     spec,
+			props:    props,
+			syms:     syms,
+			panic:    pe,
+			redact:   rd,
+		}
+		cr.save(o, l)
+	}
+	config.resolver = &safeResolver{Resolver: r, crashed: crash}
+	var rec *recorder
+	if o.CaptureDir != "" {
+		rec = newRecorder(config.resolver, props, syms, *spec, o)
+		config.resolver = rec
+	}
+	if o.LogTrace() {
+		config.resolver = &tracingResolver{Resolver: config.resolver, log: l, redact: rd}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			buf, err = nil, recovered(r)
+		}
+		var pe *panicError
+		if errors.As(err, &pe) {
+			crash("EnchCreateImage", pe)
+		}
+		size := 0
+		if buf != nil {
+			size = buf.len
+		}
+		renderMetrics.record(config.Name(), ErrorCode(err), time.Since(start), size)
+		if err != nil {
+			l.Error(
+				"Failed to create image",
+				slog.String("error", rd.error(err)),
+				slog.String("rc", ErrorCode(err).String()),
+			)
+		} else {
+			l.Info(
+				"Created image",
+				slog.Int("size", buf.len),
+				slog.String("format", spec.Format.String()),
+				slog.String("colorspace", spec.ColorSpace.String()),
+				slog.Duration("duration", time.Since(start)),
+			)
+			logFontCacheStats(l)
+		}
+		if rec != nil {
+			rec.save(o.CaptureDir, id, *spec, buf, err, l)
+		}
+	}()
+
+	l.Info(
+		"Creating image",
+		slog.Int("width", int(spec.Width)),
+		slog.Int("height", int(spec.Height)),
+		slog.Int("dpi", int(spec.DPI)),
+		slog.String("format", spec.Format.String()),
+		slog.String("colorspace", spec.ColorSpace.String()),
+	)
+	l.Debug(
+		"Configuration",
+		slog.String("properties", rd.properties(props)),
+		slog.String("symbols", rd.symbols(syms)),
+	)
+
+	if spec.Width == 0 || spec.Height == 0 {
+		return nil, &Error{Code: InvalidValue, Err: errors.New("zero dimensions supplied")}
+	}
+
+	engine := config.Engine()
+	client := s.lookupClient(engine)
+	if client == nil {
+		err := fmt.Errorf("no implementation defined for engine '%s'", engine)
+		if s.client != nil {
+			err = fmt.Errorf("%w: engines are registered, so the SetClient client is used only without an engine property", err)
+		}
+		return nil, &Error{Code: NotImplemented, Err: err}
+	}
+
+	builder := client.NewBuilder(config)
+	if builder == nil {
+		return nil, &Error{Code: NotImplemented, Err: errors.New("configuration not supported")}
+	}
+
+	builder.SetFormat(&spec.Format, &spec.ColorSpace)
+	builder.SetSize(spec.Width, spec.Height, spec.DPI)
+
+	return render(config, builder, o)
+}
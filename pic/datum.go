@@ -0,0 +1,12 @@
+package pic
+
+import "time"
+
+// Datum represents a data value resolved by Designer/Generate. Only the
+// field corresponding to Type is set.
+type Datum struct {
+	Type    DataType
+	Integer int32     // Integer
+	Number  float64   // Number or Currency
+	Time    time.Time // Date or Time
+}
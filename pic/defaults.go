@@ -0,0 +1,170 @@
+package pic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The cfg files of a chart engine give the default colours and fonts of
+// properties as tokens, such as data.colors=d0,d1,d2 and titleFont=d10,
+// which Designer expands into colour and font values before calling
+// EnchCreateImage. A DefaultsTable expands them in the same way for tools
+// and tests that read the cfg files without Designer.
+
+// DefaultPalette holds the colours of the tokens d0 to d7. Assign a palette
+// of up to 8 colours to change the colours expanded by the tables created by
+// NewDefaultsTable.
+var DefaultPalette = []Color{
+	{R: 78, G: 121, B: 167, C: 53, M: 28, Y: 0, K: 35}, // d0 blue
+	{R: 242, G: 142, B: 43, C: 0, M: 41, Y: 82, K: 5},  // d1 orange
+	{R: 225, G: 87, B: 89, C: 0, M: 61, Y: 60, K: 12},  // d2 red
+	{R: 118, G: 183, B: 178, C: 36, M: 0, Y: 3, K: 28}, // d3 teal
+	{R: 89, G: 161, B: 79, C: 45, M: 0, Y: 51, K: 37},  // d4 green
+	{R: 237, G: 201, B: 72, C: 0, M: 15, Y: 70, K: 7},  // d5 yellow
+	{R: 176, G: 122, B: 161, C: 0, M: 31, Y: 9, K: 31}, // d6 purple
+	{R: 255, G: 157, B: 167, C: 0, M: 38, Y: 35, K: 0}, // d7 pink
+}
+
+// DefaultFonts holds the fonts of the tokens d8 onwards: d8 for text such as
+// labels and legends, d9 for subtitles and d10 for titles. Each is the
+// default font of Designer/Generate in black, as the GUIDs of other fonts
+// depend on the installation; assign fonts with the GUIDs of fonts resolved
+// by your resolver to tell them apart.
+var DefaultFonts = []Font{
+	{Color: DefaultColor}, // d8 text
+	{Color: DefaultColor}, // d9 subtitles
+	{Color: DefaultColor}, // d10 titles
+}
+
+// firstFontToken is the number of the token of the first font.
+const firstFontToken = 8
+
+// DefaultsTable expands the tokens of the default colours and fonts in cfg
+// files. The tokens d0 to d7 are the colours of the palette, repeating the
+// palette if it has fewer than 8 colours, and the tokens d8 onwards are the
+// fonts in order.
+type DefaultsTable struct {
+	Palette []Color
+	Fonts   []Font
+}
+
+// NewDefaultsTable creates a table of DefaultPalette and DefaultFonts.
+func NewDefaultsTable() *DefaultsTable {
+	return &DefaultsTable{Palette: DefaultPalette, Fonts: DefaultFonts}
+}
+
+// token gets the number of a token such as d10.
+func token(v Value) (int, bool) {
+	if len(v) < 2 || v[0] != 'd' {
+		return 0, false
+	}
+	for i := 1; i < len(v); i++ {
+		if v[i] < '0' || v[i] > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(string(v[1:]))
+	return n, err == nil
+}
+
+// Token gets the colour or font value of a token, encoded as in the
+// properties passed to EnchCreateImage.
+func (t *DefaultsTable) Token(v Value) (string, error) {
+	n, ok := token(v)
+	switch {
+	case !ok:
+		return "", fmt.Errorf("invalid default token '%s'", v)
+	case n < firstFontToken:
+		if len(t.Palette) == 0 {
+			return "", fmt.Errorf("no color for default token '%s'", v)
+		}
+		return EncodeColor(t.Palette[n%len(t.Palette)])
+	case n-firstFontToken < len(t.Fonts):
+		return EncodeFont(t.Fonts[n-firstFontToken])
+	}
+	return "", fmt.Errorf("no font for default token '%s'", v)
+}
+
+// datasetProperties are the properties holding a dataset of colours or
+// fonts, one for each data series.
+var datasetProperties = map[string]bool{"data.colors": true, "data.fonts": true}
+
+// ExpandValue expands the value of a colour or font property, such as
+// titleFont, if it is a token. Other values are returned as they are.
+func (t *DefaultsTable) ExpandValue(v string) (string, error) {
+	if _, ok := token(Value(v)); !ok {
+		return v, nil
+	}
+	return t.Token(Value(v))
+}
+
+// ExpandDataset expands the value of data.colors or data.fonts if it is a
+// dataset of tokens, such as d0,d1|d2,d3 or d8, in which case the dataset
+// of the expanded values is returned, even for a single token. Other values
+// are returned as they are.
+func (t *DefaultsTable) ExpandDataset(v string) (string, error) {
+	if v == "" || v[0] == ascSOH {
+		return v, nil
+	}
+	var ds Dataset
+	for _, set := range strings.Split(v, "|") {
+		var values []Value
+		for _, val := range strings.Split(set, ",") {
+			if _, ok := token(Value(val)); !ok {
+				return v, nil
+			}
+			values = append(values, Value(val))
+		}
+		ds = append(ds, values)
+	}
+
+	for _, set := range ds {
+		for j, val := range set {
+			expanded, err := t.Token(val)
+			if err != nil {
+				return "", err
+			}
+			set[j] = Value(expanded)
+		}
+	}
+	return EncodeDataset(ds)
+}
+
+// Expand expands the tokens in properties in the form passed to
+// EnchCreateImage, one name=value pair per line, such as the contents of a
+// cfg file. As in Designer, only colour and font properties are expanded:
+// data.colors and data.fonts with ExpandDataset, and the properties named,
+// those of type cp and fp in the engine's xml file, with ExpandValue.
+// Other properties, such as titles, are left as they are even if their
+// values look like tokens.
+func (t *DefaultsTable) Expand(props string, properties ...string) (string, error) {
+	named := make(map[string]bool, len(properties))
+	for _, name := range properties {
+		named[name] = true
+	}
+	lines := strings.Split(props, "\n")
+	for i, line := range lines {
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || !named[name] && !datasetProperties[name] {
+			continue
+		}
+		cr := strings.HasSuffix(value, "\r")
+		value = strings.TrimSuffix(value, "\r")
+		var expanded string
+		var err error
+		if datasetProperties[name] {
+			expanded, err = t.ExpandDataset(value)
+		} else {
+			expanded, err = t.ExpandValue(value)
+		}
+		if err != nil {
+			return "", fmt.Errorf("property '%s': %w", name, err)
+		}
+		if cr {
+			expanded += "\r"
+		}
+		lines[i] = name + "=" + expanded
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,99 @@
+package pic
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExpandValue(t *testing.T) {
+	d := &DefaultsTable{
+		Palette: []Color{DefaultColor, {R: 255, M: 100, Y: 100}},
+		Fonts:   []Font{{Color: DefaultColor}, {GUID: GUID{0xCA, 0xFE}, Color: DefaultColor, Underline: true}},
+	}
+	black, red := "1,0,0,100", "1,0,16711680,6579200"
+	text := "\x1bf00000000000000000000000000000000|1,0,0,100|0"
+	title := "\x1bfCAFE0000000000000000000000000000|1,0,0,100|1"
+	for _, test := range []struct {
+		v, want string
+	}{
+		{"d0", black},
+		{"d1", red},
+		{"d2", black},
+		{"d7", red},
+		{"d9", title},
+		{"d0,d1", "d0,d1"},
+		{"", ""},
+		{"15", "15"},
+		{"dx", "dx"},
+	} {
+		v, err := d.ExpandValue(test.v)
+		assertEqual(t, err, nil)
+		assertEqual(t, v, test.want)
+	}
+	for _, test := range []struct {
+		v, want string
+	}{
+		{"d0", "\x01" + black},
+		{"d8", "\x01" + text},
+		{"d0,d1|d1,d0", "\x01" + black + "\x1f" + red + "\x1e" + red + "\x1f" + black},
+		{"d8,d9", "\x01" + text + "\x1f" + title},
+		{"", ""},
+		{"d0,15", "d0,15"},
+		{"\x01d0", "\x01d0"},
+	} {
+		v, err := d.ExpandDataset(test.v)
+		assertEqual(t, err, nil)
+		assertEqual(t, v, test.want)
+	}
+
+	_, err := d.ExpandValue("d10")
+	assertEqual(t, err.Error(), "no font for default token 'd10'")
+	_, err = (&DefaultsTable{}).ExpandDataset("d0,d1")
+	assertEqual(t, err.Error(), "no color for default token 'd0'")
+	_, err = d.Token("x")
+	assertEqual(t, err.Error(), "invalid default token 'x'")
+}
+
+func TestExpand(t *testing.T) {
+	props, err := NewDefaultsTable().Expand("bgColor=15\r\ntitleFont=d10\r\n\r\ntitle=d3\r\nlabel=d1", "bgColor", "titleFont")
+	assertEqual(t, err, nil)
+	assertEqual(t, props, "bgColor=15\r\ntitleFont=\x1bf00000000000000000000000000000000|1,0,0,100|0\r\n\r\ntitle=d3\r\nlabel=d1")
+	_, err = NewDefaultsTable().Expand("data.fonts=d8,d12")
+	assertEqual(t, err.Error(), "property 'data.fonts': no font for default token 'd12'")
+}
+
+func TestExpandSingleToken(t *testing.T) {
+	props, err := NewDefaultsTable().Expand("data.fonts=d8\ndata.colors=d0\ndata.labels=d1")
+	assertEqual(t, err, nil)
+	c := newConfig(newMockCallback(), props, "")
+	fonts := c.DataFonts()
+	assertEqual(t, len(fonts), 1)
+	assertEqual(t, fonts[0], DefaultFonts[0])
+	colors := c.DataColors()
+	assertEqual(t, len(colors), 1)
+	assertEqual(t, colors[0], DefaultPalette[0])
+	assertEqual(t, c.DataLabels()[0].Text(), "d1")
+	assertEqual(t, c.Err(), nil)
+}
+
+func TestExpandConfigFile(t *testing.T) {
+	b, err := os.ReadFile("../example/go-chart/config/go-chart.cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	props, err := NewDefaultsTable().Expand(string(b), "titleFont", "bgColor", "legendColor", "axisFont")
+	assertEqual(t, err, nil)
+	c := newConfig(newMockCallback(), props, "")
+	data := c.Data()
+	assertEqual(t, c.Err(), nil)
+	assertEqual(t, len(data.Colors), 3)
+	for i, color := range data.Colors {
+		assertEqual(t, color, DefaultPalette[i])
+	}
+	assertEqual(t, len(data.Fonts), 4)
+	assertEqual(t, data.Fonts[0], DefaultFonts[0])
+	font, err := c.FontE("titleFont")
+	assertEqual(t, err, nil)
+	assertEqual(t, font, DefaultFonts[2])
+	assertEqual(t, c.Color("bgColor"), Color{R: 255, G: 255, B: 255})
+}
@@ -6,10 +6,27 @@
 //
 // To use this package you must implement the pic.Client and pic.Builder
 // interfaces. Tell pic about your Client implementation by calling the
-// pic.SetClient() method in the init() function of your main.go file.
-// When Designer/Generate needs to create a chart image, pic will call
-// your Client.NewBuilder() method to create the image via your Builder
-// implementation.
+// pic.Register() method with the id of your engine's propertyTemplate, and
+// pic.SetOptions() to specify the options, in the init() function of your
+// main.go file. When Designer/Generate needs to create a chart image, pic
+// will call your Client.NewBuilder() method to create the image via your
+// Builder implementation.
+//
+// To host more than one chart engine in a single shared library, call
+// pic.Register() for each engine. The engine property of the chart
+// configuration determines which Client is used, and NotImplemented is
+// returned for an engine that has not been registered. A library that calls
+// pic.SetClient() instead of pic.Register() keeps working as before: its
+// Client is used for every configuration, whatever the engine. Once any
+// engine is registered, the Client given to pic.SetClient() is used only for
+// configurations without an engine property.
+//
+// Messages are logged with log/slog, in text or JSON format, to the log file
+// named in the Options or to a slog.Handler of your own. Every message about
+// a chart image carries a call ID and the configuration name so that the
+// messages of concurrent calls can be told apart. The log level, log file,
+// strict mode and timeouts can be overridden without rebuilding, from the
+// PIC_OPTIONS environment variable or an .ini file next to the library.
 //
 // You must build your application with the -buildmode=c-shared option in
 // order to create a .dll file that can be loaded by Designer and Generate.
@@ -0,0 +1,305 @@
+package pic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The encoders create the configuration settings decoded by Config, as
+// passed to EnchCreateImage by Designer/Generate, for example to write the
+// properties of a chart in a test. A value that Config would decode
+// differently, such as one starting with the DLE character of a symbol
+// reference, cannot be encoded.
+
+var valueTypes = map[DataType]byte{
+	Integer:  'i',
+	Number:   'n',
+	Date:     'd',
+	Time:     't',
+	Currency: '$',
+}
+
+// EncodeValue encodes the text of a value of a data type, so that Config
+// resolves it as that type.
+func EncodeValue(t DataType, text string) (Value, error) {
+	if t == Neutral {
+		if text != "" && (text[0] == ascESC || text[0] == ascDLE) {
+			return "", fmt.Errorf("cannot encode value '%s' starting with ESC or DLE", text)
+		}
+		return Value(text), nil
+	}
+	code, ok := valueTypes[t]
+	if !ok {
+		return "", fmt.Errorf("cannot encode value of type %v", t)
+	}
+	return Value([]byte{ascESC, code}) + Value(text), nil
+}
+
+// EncodeDataset encodes a dataset. The sets are separated by '|' and the
+// values by ',', unless a value contains either, in which case the sets
+// are separated by RS and the values by US.
+func EncodeDataset(ds Dataset) (string, error) {
+	if len(ds) == 0 {
+		return "", errors.New("cannot encode empty dataset")
+	}
+	setSep, valSep := "|", ","
+	for i, set := range ds {
+		if len(set) == 0 {
+			return "", fmt.Errorf("cannot encode empty set %d of dataset", i)
+		}
+		for _, v := range set {
+			if v != "" && v[0] == ascDLE {
+				return "", fmt.Errorf("cannot encode dataset value '%s' starting with DLE", v)
+			}
+			if strings.ContainsAny(string(v), "|,") || (i == 0 && v != "" && v[0] == ascSOH) {
+				setSep, valSep = string(ascRS), string(ascUS)
+			}
+		}
+	}
+
+	var b strings.Builder
+	if setSep != "|" {
+		b.WriteByte(ascSOH)
+	}
+	for i, set := range ds {
+		if i > 0 {
+			b.WriteString(setSep)
+		}
+		for j, v := range set {
+			if strings.ContainsAny(string(v), string([]byte{ascRS, ascUS})) {
+				return "", fmt.Errorf("cannot encode dataset value '%s' containing RS or US", v)
+			}
+			if j > 0 {
+				b.WriteString(valSep)
+			}
+			b.WriteString(string(v))
+		}
+	}
+	return b.String(), nil
+}
+
+// EncodeColor encodes a colour with its RGB and CMYK values. The CMYK
+// values are percentages, so cannot be more than 100.
+func EncodeColor(c Color) (string, error) {
+	if c.C > 100 || c.M > 100 || c.Y > 100 || c.K > 100 {
+		return "", fmt.Errorf("cannot encode color with CMYK %d,%d,%d,%d", c.C, c.M, c.Y, c.K)
+	}
+	rgb := int32(c.R)<<16 | int32(c.G)<<8 | int32(c.B)
+	cmyk := int32(c.C)<<24 | int32(c.M)<<16 | int32(c.Y)<<8 | int32(c.K)
+	return fmt.Sprintf("%d,0,%d,%d", RGB, rgb, cmyk), nil
+}
+
+// EncodeFont encodes a font. The colour and underlining of a font style
+// are those of the style, so cannot be set in the Font.
+func EncodeFont(f Font) (string, error) {
+	if f.IsStyle {
+		if f.Color != (Color{}) || f.Underline {
+			return "", errors.New("cannot encode font style with color or underline")
+		}
+		return fmt.Sprintf("%cf$%v", ascESC, f.GUID), nil
+	}
+	color, err := EncodeColor(f.Color)
+	if err != nil {
+		return "", err
+	}
+	underline := 0
+	if f.Underline {
+		underline = 1
+	}
+	return fmt.Sprintf("%cf%v|%s|%d", ascESC, f.GUID, color, underline), nil
+}
+
+// EncodeDataStyles encodes the data styles of each value of a dataset. The
+// settings of each style are separated by STX, in order of name. Nil and
+// empty Settings are encoded alike.
+func EncodeDataStyles(styles DataStyles) (string, error) {
+	ds := make(Dataset, len(styles))
+	for i, set := range styles {
+		ds[i] = make([]Value, len(set))
+		for j, style := range set {
+			v, err := encodeDataStyle(style)
+			if err != nil {
+				return "", err
+			}
+			ds[i][j] = v
+		}
+	}
+	return EncodeDataset(ds)
+}
+
+func encodeDataStyle(style DataStyle) (Value, error) {
+	if style.Type == "" && len(style.Settings) == 0 {
+		return "", nil
+	}
+	if style.Type == "" || strings.ContainsRune(style.Type, ':') {
+		return "", fmt.Errorf("cannot encode data style type '%s'", style.Type)
+	}
+	names := make([]string, 0, len(style.Settings))
+	for name := range style.Settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(style.Type)
+	b.WriteByte(':')
+	for _, name := range names {
+		v := style.Settings[name]
+		if name == "" || strings.ContainsAny(name, "=\r"+string(ascSTX)) {
+			return "", fmt.Errorf("cannot encode data style setting '%s'", name)
+		}
+		err := checkSetting(string(v), ascSTX)
+		if err == nil {
+			err = checkValue(string(v))
+		}
+		if err != nil {
+			return "", fmt.Errorf("data style setting '%s': %w", name, err)
+		}
+		b.WriteByte(ascSTX)
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(string(v))
+	}
+	return Value(b.String()), nil
+}
+
+// checkSetting checks that loadSettings decodes the value of a setting as
+// it is.
+func checkSetting(v string, sep byte) error {
+	if strings.IndexByte(v, sep) >= 0 {
+		return fmt.Errorf("cannot encode value '%s' containing %q", v, sep)
+	}
+	if strings.HasPrefix(v, "\r") || strings.HasSuffix(v, "\r") {
+		return fmt.Errorf("cannot encode value '%s' starting or ending with CR", v)
+	}
+	return nil
+}
+
+// checkValue checks that a value is not decoded as a symbol reference.
+func checkValue(v string) error {
+	if v != "" && v[0] == ascDLE {
+		return fmt.Errorf("cannot encode value '%s' starting with DLE", v)
+	}
+	return nil
+}
+
+// PropertiesBuilder builds the properties and symbols of a chart in the
+// form passed to EnchCreateImage. The first error is kept, and returned by
+// Build.
+//
+//	var b pic.PropertiesBuilder
+//	b.Set("config", "pie")
+//	b.SetSymbol("title", "Title", "Sales")
+//	b.SetDataset("data.values", pic.Dataset{{"1", "2", "3"}})
+//	props, syms, err := b.Build()
+type PropertiesBuilder struct {
+	props, syms []setting
+	err         error
+}
+
+type setting struct {
+	name, value string
+}
+
+func (b *PropertiesBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *PropertiesBuilder) set(settings *[]setting, name, value string) {
+	if name == "" || strings.ContainsAny(name, "=\n\r") {
+		b.fail(fmt.Errorf("invalid property name '%s'", name))
+		return
+	}
+	for i := range *settings {
+		if (*settings)[i].name == name {
+			(*settings)[i].value = value
+			return
+		}
+	}
+	*settings = append(*settings, setting{name, value})
+}
+
+// Set sets a property to a value.
+func (b *PropertiesBuilder) Set(name string, v Value) {
+	err := checkSetting(string(v), '\n')
+	if err == nil {
+		err = checkValue(string(v))
+	}
+	if err != nil {
+		b.fail(fmt.Errorf("property '%s': %w", name, err))
+		return
+	}
+	b.set(&b.props, name, string(v))
+}
+
+// SetSymbol sets a property to refer to a symbol, and sets the symbol to a
+// value in the symbol table. The value of a dataset property can refer to a
+// symbol whose value is a list of values separated by ','.
+func (b *PropertiesBuilder) SetSymbol(name, symbol string, v Value) {
+	if strings.ContainsAny(symbol, "|,"+string([]byte{ascRS, ascUS})) {
+		b.fail(fmt.Errorf("invalid symbol name '%s'", symbol))
+		return
+	}
+	if err := checkSetting(string(v), '\n'); err != nil {
+		b.fail(fmt.Errorf("symbol '%s': %w", symbol, err))
+		return
+	}
+	b.set(&b.props, name, string(ascDLE)+symbol)
+	b.set(&b.syms, symbol, string(v))
+}
+
+// SetDataset sets a property to a dataset.
+func (b *PropertiesBuilder) SetDataset(name string, ds Dataset) {
+	v, err := EncodeDataset(ds)
+	b.setEncoded(name, v, err)
+}
+
+// SetColor sets a property to a colour.
+func (b *PropertiesBuilder) SetColor(name string, c Color) {
+	v, err := EncodeColor(c)
+	b.setEncoded(name, v, err)
+}
+
+// SetFont sets a property to a font.
+func (b *PropertiesBuilder) SetFont(name string, f Font) {
+	v, err := EncodeFont(f)
+	b.setEncoded(name, v, err)
+}
+
+// SetDataStyles sets a property to data styles.
+func (b *PropertiesBuilder) SetDataStyles(name string, styles DataStyles) {
+	v, err := EncodeDataStyles(styles)
+	b.setEncoded(name, v, err)
+}
+
+func (b *PropertiesBuilder) setEncoded(name, v string, err error) {
+	if err != nil {
+		b.fail(fmt.Errorf("property '%s': %w", name, err))
+		return
+	}
+	b.Set(name, Value(v))
+}
+
+// Build gets the properties and symbols, one name=value pair per line, or
+// the first error.
+func (b *PropertiesBuilder) Build() (props, syms string, err error) {
+	if b.err != nil {
+		return "", "", b.err
+	}
+	return formatSettings(b.props), formatSettings(b.syms), nil
+}
+
+func formatSettings(settings []setting) string {
+	var b strings.Builder
+	for _, s := range settings {
+		b.WriteString(s.name)
+		b.WriteByte('=')
+		b.WriteString(s.value)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,227 @@
+package pic
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// buildConfig builds the properties with the builder and loads them.
+func buildConfig(t *testing.T, build func(b *PropertiesBuilder)) *Config {
+	t.Helper()
+	var b PropertiesBuilder
+	build(&b)
+	props, syms, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newConfig(newMockCallback(), props, syms)
+}
+
+func TestEncodeValue(t *testing.T) {
+	for _, test := range []struct {
+		t    DataType
+		text string
+	}{
+		{Neutral, "text"},
+		{Integer, "42"},
+		{Number, "4.2"},
+		{Date, "31/12/2020"},
+		{Time, "23:59:59"},
+		{Currency, "$4.20"},
+	} {
+		v, err := EncodeValue(test.t, test.text)
+		assertEqual(t, err, nil)
+		c := buildConfig(t, func(b *PropertiesBuilder) { b.Set("value", v) })
+		assertEqual(t, c.Value("value").Type(), test.t)
+		assertEqual(t, c.Value("value").Text(), test.text)
+	}
+
+	_, err := EncodeValue(NotSet, "x")
+	assertEqual(t, err.Error(), "cannot encode value of type NotSet")
+	_, err = EncodeValue(Neutral, "\x10sym")
+	assertEqual(t, err.Error(), "cannot encode value '\x10sym' starting with ESC or DLE")
+}
+
+func TestEncodeDataset(t *testing.T) {
+	for _, test := range []struct {
+		ds   Dataset
+		want string
+	}{
+		{Dataset{{""}}, ""},
+		{Dataset{{"1", "2"}, {"3", ""}}, "1,2|3,"},
+		{Dataset{{"a,b", "c"}, {"d|e"}}, "\x01a,b\x1fc\x1ed|e"},
+		{Dataset{{"\x01"}}, "\x01\x01"},
+		{Dataset{{"x"}, {"\x01"}}, "x|\x01"},
+	} {
+		s, err := EncodeDataset(test.ds)
+		assertEqual(t, err, nil)
+		assertEqual(t, s, test.want)
+		c := buildConfig(t, func(b *PropertiesBuilder) { b.SetDataset("data", test.ds) })
+		if ds := c.Dataset("data"); !reflect.DeepEqual(ds, test.ds) {
+			t.Errorf("Dataset %q decoded as %q", test.ds, ds)
+		}
+	}
+
+	for _, test := range []struct {
+		ds  Dataset
+		err string
+	}{
+		{Dataset{}, "cannot encode empty dataset"},
+		{Dataset{{"1"}, {}}, "cannot encode empty set 1 of dataset"},
+		{Dataset{{"\x10sym"}}, "cannot encode dataset value '\x10sym' starting with DLE"},
+		{Dataset{{"a,b", "c\x1fd"}}, "cannot encode dataset value 'c\x1fd' containing RS or US"},
+	} {
+		_, err := EncodeDataset(test.ds)
+		assertEqual(t, err.Error(), test.err)
+	}
+}
+
+func TestEncodeColor(t *testing.T) {
+	for _, color := range []Color{
+		DefaultColor,
+		{},
+		{R: 255, G: 51, B: 1, C: 0, M: 80, Y: 99, K: 100},
+	} {
+		c := buildConfig(t, func(b *PropertiesBuilder) { b.SetColor("color", color) })
+		decoded, err := c.ColorE("color")
+		assertEqual(t, err, nil)
+		assertEqual(t, decoded, color)
+	}
+
+	s, err := EncodeColor(Color{R: 255, G: 51, B: 51, M: 80, Y: 80})
+	assertEqual(t, err, nil)
+	assertEqual(t, s, "1,0,16724787,5263360")
+	_, err = EncodeColor(Color{C: 101})
+	assertEqual(t, err.Error(), "cannot encode color with CMYK 101,0,0,0")
+}
+
+func TestEncodeFont(t *testing.T) {
+	guid := GUID{0xCA, 0xFE, 14: 0xF0, 15: 0x0D}
+	for _, f := range []Font{
+		DefaultFont,
+		{GUID: guid, Color: Color{R: 255, K: 10}, Underline: true},
+		{IsStyle: true, GUID: guid},
+	} {
+		c := buildConfig(t, func(b *PropertiesBuilder) { b.SetFont("font", f) })
+		decoded, err := c.FontE("font")
+		assertEqual(t, err, nil)
+		assertEqual(t, decoded, f)
+	}
+
+	s, err := EncodeFont(Font{GUID: guid, Color: DefaultColor})
+	assertEqual(t, err, nil)
+	assertEqual(t, s, "\x1bfCAFE000000000000000000000000F00D|1,0,0,100|0")
+	_, err = EncodeFont(Font{IsStyle: true, Underline: true})
+	assertEqual(t, err.Error(), "cannot encode font style with color or underline")
+}
+
+func TestEncodeDataStyles(t *testing.T) {
+	styles := DataStyles{
+		{
+			{Type: "line", Settings: map[string]Value{"lineStyle": "solid", "lineWidth": "7200"}},
+			{Type: "custom", Settings: map[string]Value{"customFmt": "{label} ({value}), {percent}|"}},
+		},
+		{
+			{},
+			{Type: "bar", Settings: map[string]Value{}},
+		},
+	}
+	c := buildConfig(t, func(b *PropertiesBuilder) { b.SetDataStyles("data.styles", styles) })
+	decoded := c.DataStyles()
+	assertEqual(t, c.Err(), nil)
+	if !reflect.DeepEqual(decoded, styles) {
+		t.Errorf("Data styles %v decoded as %v", styles, decoded)
+	}
+
+	s, err := EncodeDataStyles(DataStyles{{styles[0][0]}})
+	assertEqual(t, err, nil)
+	assertEqual(t, s, "line:\x02lineStyle=solid\x02lineWidth=7200")
+
+	for _, test := range []struct {
+		style DataStyle
+		err   string
+	}{
+		{DataStyle{Settings: map[string]Value{"a": "b"}}, "cannot encode data style type ''"},
+		{DataStyle{Type: "a:b"}, "cannot encode data style type 'a:b'"},
+		{DataStyle{Type: "line", Settings: map[string]Value{"a=b": "c"}}, "cannot encode data style setting 'a=b'"},
+		{DataStyle{Type: "line", Settings: map[string]Value{"a": "\x10sym"}}, "data style setting 'a': cannot encode value '\x10sym' starting with DLE"},
+		{DataStyle{Type: "line", Settings: map[string]Value{"a": "b\x02c"}}, "data style setting 'a': cannot encode value 'b\x02c' containing '\\x02'"},
+	} {
+		_, err := EncodeDataStyles(DataStyles{{test.style}})
+		assertEqual(t, err.Error(), test.err)
+	}
+}
+
+func TestPropertiesBuilder(t *testing.T) {
+	var b PropertiesBuilder
+	b.Set("engine", "go-chart")
+	b.Set("config", "bar")
+	b.SetSymbol("title", "Title", "Sales in €")
+	b.SetSymbol("data.values", "Values", "1,2,3")
+	b.SetDataset("data.labels", Dataset{{"a", "b", "c"}})
+	b.Set("config", "pie")
+	props, syms, err := b.Build()
+	assertEqual(t, err, nil)
+	assertEqual(t, props, "engine=go-chart\nconfig=pie\ntitle=\x10Title\ndata.values=\x10Values\ndata.labels=a,b,c\n")
+	assertEqual(t, syms, "Title=Sales in €\nValues=1,2,3\n")
+
+	c := newConfig(newMockCallback(), props, syms)
+	assertEqual(t, c.Name(), "pie")
+	assertEqual(t, c.Value("title").Text(), "Sales in €")
+	assertEqual(t, fmt.Sprint(c.DataValues()), "[[1 2 3]]")
+}
+
+func TestPropertiesBuilderErrors(t *testing.T) {
+	for _, test := range []struct {
+		build func(b *PropertiesBuilder)
+		err   string
+	}{
+		{func(b *PropertiesBuilder) { b.Set("a=b", "c") }, "invalid property name 'a=b'"},
+		{func(b *PropertiesBuilder) { b.Set("", "c") }, "invalid property name ''"},
+		{func(b *PropertiesBuilder) { b.Set("a", "b\nc") }, "property 'a': cannot encode value 'b\nc' containing '\\n'"},
+		{func(b *PropertiesBuilder) { b.Set("a", "b\r") }, "property 'a': cannot encode value 'b\r' starting or ending with CR"},
+		{func(b *PropertiesBuilder) { b.Set("a", "\x10b") }, "property 'a': cannot encode value '\x10b' starting with DLE"},
+		{func(b *PropertiesBuilder) { b.SetSymbol("a", "b,c", "d") }, "invalid symbol name 'b,c'"},
+		{func(b *PropertiesBuilder) { b.SetDataset("a", Dataset{}) }, "property 'a': cannot encode empty dataset"},
+		{func(b *PropertiesBuilder) {
+			b.SetColor("a", Color{K: 255})
+			b.Set("b=", "")
+		}, "property 'a': cannot encode color with CMYK 0,0,0,255"},
+	} {
+		var b PropertiesBuilder
+		test.build(&b)
+		props, syms, err := b.Build()
+		assertEqual(t, props+syms, "")
+		if err == nil || err.Error() != test.err {
+			t.Errorf("Error %v, want %s", err, test.err)
+		}
+	}
+}
+
+func FuzzEncodeDataset(f *testing.F) {
+	f.Add("1\t2\t3\n4\t5\t6")
+	f.Add("a,b\tc|d")
+	f.Add("\x01\n\x01")
+	f.Fuzz(func(t *testing.T, input string) {
+		var ds Dataset
+		for _, line := range strings.Split(input, "\n") {
+			var set []Value
+			for _, v := range strings.Split(line, "\t") {
+				set = append(set, Value(v))
+			}
+			ds = append(ds, set)
+		}
+		var b PropertiesBuilder
+		b.SetDataset("data", ds)
+		props, syms, err := b.Build()
+		if err != nil {
+			return
+		}
+		c := newConfig(newMockCallback(), props, syms)
+		if decoded := c.Dataset("data"); !reflect.DeepEqual(decoded, ds) {
+			t.Errorf("Dataset %q encoded as %q decoded as %q", ds, props, decoded)
+		}
+	})
+}
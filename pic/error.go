@@ -0,0 +1,58 @@
+package pic
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error represents a failure to create a chart image which Designer/Generate
+// should report with a specific ReturnCode. A Builder can return an Error
+// from Render to control the ReturnCode returned by EnchCreateImage.
+type Error struct {
+	Code     ReturnCode // Code returned to Designer/Generate.
+	Property string     // Name of the offending property, if known.
+	Err      error      // Underlying cause, if any.
+}
+
+func (e *Error) Error() string {
+	s := e.Code.String()
+	if e.Property != "" {
+		s += fmt.Sprintf(": property '%s'", e.Property)
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// Unwrap returns the underlying cause of the error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode determines the ReturnCode for an error. OK is returned for a
+// nil error, and Failed is returned if the error does not wrap an Error or
+// wraps an Error with the OK code, so that a failure is never reported as a
+// success.
+func ErrorCode(err error) ReturnCode {
+	if err == nil {
+		return OK
+	}
+	var e *Error
+	if errors.As(err, &e) && e.Code != OK {
+		return e.Code
+	}
+	return Failed
+}
+
+// propertyError names the property in an Error that does not already
+// name one.
+func propertyError(err error, name string) error {
+	var e *Error
+	if errors.As(err, &e) && e.Property == "" {
+		named := *e
+		named.Property = name
+		return &named
+	}
+	return err
+}
@@ -0,0 +1,25 @@
+package pic
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorString(t *testing.T) {
+	cause := errors.New("bad value")
+	assertEqual(t, (&Error{Code: MissingProperty}).Error(), "MissingProperty")
+	assertEqual(t, (&Error{Code: InvalidValue, Property: "color"}).Error(), "InvalidValue: property 'color'")
+	assertEqual(t, (&Error{Code: InvalidValue, Property: "color", Err: cause}).Error(), "InvalidValue: property 'color': bad value")
+	assertEqual(t, errors.Unwrap(&Error{Code: InvalidValue, Err: cause}), cause)
+}
+
+func TestErrorCode(t *testing.T) {
+	assertEqual(t, ErrorCode(nil), OK)
+	assertEqual(t, ErrorCode(errors.New("failed")), Failed)
+	assertEqual(t, ErrorCode(&Error{Code: EmptyDataString}), EmptyDataString)
+	wrapped := fmt.Errorf("wrapped: %w", &Error{Code: UnresolvedFont})
+	assertEqual(t, ErrorCode(wrapped), UnresolvedFont)
+	assertEqual(t, ErrorCode(&Error{Err: errors.New("no code")}), Failed)
+	assertEqual(t, ErrorCode(&Error{Code: OK, Property: "title"}), Failed)
+}
@@ -17,6 +17,9 @@ type Font struct {
 var DefaultFont = Font{}
 
 func (f *Font) parse(ds Dataset) error {
+	if len(ds) == 0 || len(ds[0]) != 1 {
+		return fmt.Errorf("invalid font set %v", ds)
+	}
 	v := ds[0][0]
 	if len(v) < 2 || v[0] != ascESC || v[1] != 'f' {
 		return fmt.Errorf("invalid font value '%s'", v)
@@ -43,8 +46,11 @@ func (f *Font) parse(ds Dataset) error {
 		if err = f.Color.parse(ds[1]); err != nil {
 			return err
 		}
+		if len(ds[2]) != 1 {
+			return fmt.Errorf("invalid font underline %v", ds[2])
+		}
 		if f.Underline, err = strconv.ParseBool(string(ds[2][0])); err != nil {
-			return err
+			return fmt.Errorf("invalid font underline '%s'", ds[2][0])
 		}
 	}
 
@@ -0,0 +1,145 @@
+package pic
+
+import (
+	"container/list"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/freetype/truetype"
+)
+
+// DefaultFontCacheSize is the default maximum size in bytes of the font
+// files held in the font cache.
+const DefaultFontCacheSize = 32 << 20
+
+// fontCache is a process-wide cache of parsed TrueType fonts shared by all
+// calls to EnchCreateImage. Fonts are keyed by file name and modification
+// time, and the least recently used fonts are evicted once the total size
+// of the cached font files exceeds the limit.
+type fontCache struct {
+	mu      sync.Mutex
+	limit   int64
+	size    int64
+	lru     *list.List // Most recently used at the front.
+	entries map[string]*list.Element
+	parse   func(data []byte) (*truetype.Font, error)
+
+	hits, misses, evictions uint64
+}
+
+type fontCacheEntry struct {
+	filename string
+	modTime  time.Time
+	size     int64
+	font     *truetype.Font
+}
+
+// fontCacheStats reports the activity of the font cache.
+type fontCacheStats struct {
+	hits, misses, evictions uint64
+	fonts                   int
+	size                    int64
+}
+
+var fonts = newFontCache(DefaultFontCacheSize)
+
+func newFontCache(limit int64) *fontCache {
+	return &fontCache{
+		limit:   limit,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		parse:   truetype.Parse,
+	}
+}
+
+// setLimit changes the maximum size of the cache. Zero selects the default
+// size and a negative size disables the cache.
+func (fc *fontCache) setLimit(limit int64) {
+	if limit == 0 {
+		limit = DefaultFontCacheSize
+	}
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.limit = limit
+	fc.evict()
+}
+
+// load gets the parsed font from the cache, or reads and parses the font
+// file if it is not cached or has been modified since it was cached.
+func (fc *fontCache) load(filename string) (*truetype.Font, error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	fc.mu.Lock()
+	if e, ok := fc.entries[filename]; ok {
+		entry := e.Value.(*fontCacheEntry)
+		if entry.modTime.Equal(fi.ModTime()) {
+			fc.hits++
+			fc.lru.MoveToFront(e)
+			fc.mu.Unlock()
+			return entry.font, nil
+		}
+	}
+	fc.misses++
+	fc.mu.Unlock()
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	font, err := fc.parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	fc.add(&fontCacheEntry{
+		filename: filename,
+		modTime:  fi.ModTime(),
+		size:     int64(len(data)),
+		font:     font,
+	})
+	return font, nil
+}
+
+func (fc *fontCache) add(entry *fontCacheEntry) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if e, ok := fc.entries[entry.filename]; ok {
+		fc.remove(e)
+	}
+	if entry.size > fc.limit {
+		return
+	}
+	fc.entries[entry.filename] = fc.lru.PushFront(entry)
+	fc.size += entry.size
+	fc.evict()
+}
+
+func (fc *fontCache) evict() {
+	for fc.size > fc.limit && fc.lru.Len() > 0 {
+		fc.remove(fc.lru.Back())
+		fc.evictions++
+	}
+}
+
+func (fc *fontCache) remove(e *list.Element) {
+	entry := fc.lru.Remove(e).(*fontCacheEntry)
+	delete(fc.entries, entry.filename)
+	fc.size -= entry.size
+}
+
+func (fc *fontCache) stats() fontCacheStats {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fontCacheStats{
+		hits:      fc.hits,
+		misses:    fc.misses,
+		evictions: fc.evictions,
+		fonts:     fc.lru.Len(),
+		size:      fc.size,
+	}
+}
@@ -0,0 +1,103 @@
+package pic
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/freetype/truetype"
+)
+
+func newTestFontCache(limit int64) (*fontCache, *int) {
+	parsed := 0
+	fc := newFontCache(limit)
+	fc.parse = func(data []byte) (*truetype.Font, error) {
+		parsed++
+		return &truetype.Font{}, nil
+	}
+	return fc, &parsed
+}
+
+func writeFontFile(t *testing.T, dir, name string, size int) string {
+	filename := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(filename, make([]byte, size), 0644); err != nil {
+		t.Fatalf("Failed to write font file: %v", err)
+	}
+	return filename
+}
+
+func TestFontCacheHit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fonts")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir")
+	}
+	defer os.RemoveAll(dir)
+
+	fc, parsed := newTestFontCache(1000)
+	filename := writeFontFile(t, dir, "a.ttf", 100)
+	f1, err := fc.load(filename)
+	assertEqual(t, err, nil)
+	f2, err := fc.load(filename)
+	assertEqual(t, err, nil)
+	assertEqual(t, f1, f2)
+	assertEqual(t, *parsed, 1)
+	fcs := fc.stats()
+	assertEqual(t, fcs.hits, uint64(1))
+	assertEqual(t, fcs.misses, uint64(1))
+	assertEqual(t, fcs.size, int64(100))
+}
+
+func TestFontCacheModified(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fonts")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir")
+	}
+	defer os.RemoveAll(dir)
+
+	fc, parsed := newTestFontCache(1000)
+	filename := writeFontFile(t, dir, "a.ttf", 100)
+	fc.load(filename)
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filename, later, later)
+	fc.load(filename)
+	assertEqual(t, *parsed, 2)
+	assertEqual(t, fc.stats().fonts, 1)
+	assertEqual(t, fc.stats().size, int64(100))
+}
+
+func TestFontCacheEviction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fonts")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir")
+	}
+	defer os.RemoveAll(dir)
+
+	fc, parsed := newTestFontCache(250)
+	a := writeFontFile(t, dir, "a.ttf", 100)
+	b := writeFontFile(t, dir, "b.ttf", 100)
+	c := writeFontFile(t, dir, "c.ttf", 100)
+	fc.load(a)
+	fc.load(b)
+	fc.load(a)
+	fc.load(c) // Evicts b as the least recently used.
+	assertEqual(t, fc.stats().evictions, uint64(1))
+	assertEqual(t, fc.stats().fonts, 2)
+	fc.load(a)
+	assertEqual(t, *parsed, 3)
+	fc.load(b)
+	assertEqual(t, *parsed, 4)
+
+	fc.setLimit(-1)
+	assertEqual(t, fc.stats().fonts, 0)
+	fc.load(a)
+	fc.load(a)
+	assertEqual(t, *parsed, 6)
+}
+
+func TestFontCacheMissingFile(t *testing.T) {
+	fc, _ := newTestFontCache(1000)
+	_, err := fc.load(filepath.Join(os.TempDir(), "missing-font.ttf"))
+	assertEqual(t, err != nil, true)
+}
@@ -1,10 +1,6 @@
 package pic
 
-import (
-	"io/ioutil"
-
-	"github.com/golang/freetype/truetype"
-)
+import "github.com/golang/freetype/truetype"
 
 // FontResource represents a font resource from Designer/Generate.
 type FontResource struct {
@@ -45,14 +41,11 @@ func (fr *FontResource) loadTruetype() error {
 		if fr.Filename == "" {
 			return nil
 		}
-		data, err := ioutil.ReadFile(fr.Filename)
-		if err != nil {
-			return err
-		}
-		fr.TruetypeFont, err = truetype.Parse(data)
+		font, err := fonts.load(fr.Filename)
 		if err != nil {
 			return err
 		}
+		fr.TruetypeFont = font
 	}
 	return nil
 }
@@ -0,0 +1,133 @@
+package pic
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// The fuzz targets check that malformed configurations from Designer/Generate
+// give errors rather than panics. Run one with, for example:
+//
+//	go test -run=^$ -fuzz=FuzzFontParse
+
+func FuzzLoadSettings(f *testing.F) {
+	f.Add("title=Sales\r\nlegend=true\r\n", byte('\n'))
+	f.Add("style=solid+width=7200", byte('+'))
+	f.Add("==\n=\nx", byte('='))
+	f.Fuzz(func(t *testing.T, input string, sep byte) {
+		for k, v := range loadSettings(input, sep) {
+			if strings.Contains(k, "=") {
+				t.Errorf("Name %q contains '='", k)
+			}
+			if sep != '=' && strings.IndexByte(v, sep) >= 0 {
+				t.Errorf("Value %q contains separator %q", v, sep)
+			}
+		}
+	})
+}
+
+func FuzzLoadDataset(f *testing.F) {
+	f.Add("4,2,3,4|2,4,1,3", "")
+	f.Add(fmt.Sprintf("%c1%c2%c3", ascSOH, ascUS, ascRS), "")
+	f.Add(fmt.Sprintf("%cvalues|x", ascDLE), "values=1,2,3")
+	f.Add(string(ascSOH), "")
+	f.Fuzz(func(t *testing.T, input, symbols string) {
+		c := newConfig(newMockCallback(), "", symbols)
+		ds := c.loadDataset(input)
+		if len(ds) == 0 {
+			t.Fatal("Empty dataset")
+		}
+		for i, set := range ds {
+			if len(set) == 0 {
+				t.Errorf("Empty set %d", i)
+			}
+		}
+		// The accessors rely on the dataset never being empty.
+		c.properties["data.colors"] = input
+		c.properties["data.fonts"] = input
+		c.properties["data.labels"] = input
+		c.properties["data.titles"] = input
+		c.Data()
+	})
+}
+
+func FuzzLoadDataStyle(f *testing.F) {
+	f.Add("line:+lineStyle=solid+lineWidth=7200", "")
+	f.Add(fmt.Sprintf("custom:%ccustomFmt=%cfmt", ascSTX, ascDLE), "fmt={value}")
+	f.Add("line:", "")
+	f.Add("line", "")
+	f.Add(":", "")
+	f.Add("line:é=x", "")
+	f.Fuzz(func(t *testing.T, input, symbols string) {
+		c := newConfig(newMockCallback(), "", symbols)
+		style, err := c.loadDataStyle(Value(input))
+		if err != nil {
+			if style.Type != "" || style.Settings != nil {
+				t.Errorf("Style %v returned with error %v", style, err)
+			}
+			return
+		}
+		if input != "" && !strings.HasPrefix(input, style.Type+":") {
+			t.Errorf("Type %q is not the prefix of %q", style.Type, input)
+		}
+	})
+}
+
+func FuzzFontParse(f *testing.F) {
+	f.Add(fmt.Sprintf("%cfCAFE000000000000000000000000F00D|0,0,0,100|0", ascESC))
+	f.Add(fmt.Sprintf("%cf$CAFE000000000000000000000000F00D", ascESC))
+	f.Add(fmt.Sprintf("%cf|||", ascESC))
+	f.Add(fmt.Sprintf("%cf$", ascESC))
+	f.Add("d8")
+	f.Fuzz(func(t *testing.T, input string) {
+		c := newConfig(newMockCallback(), "", "")
+		var font Font
+		err := font.parse(c.loadDataset(input))
+		if err != nil {
+			if err.Error() == "" {
+				t.Error("Empty error")
+			}
+			return
+		}
+		guid := font.GUID.String()
+		if !strings.Contains(strings.ToUpper(input), guid) {
+			t.Errorf("GUID %s not in %q", guid, input)
+		}
+	})
+}
+
+func FuzzColorParse(f *testing.F) {
+	f.Add("15")
+	f.Add("0,4,16711935,6553600")
+	f.Add("-1")
+	f.Add("1,2,3")
+	f.Add("d0")
+	f.Fuzz(func(t *testing.T, input string) {
+		c := newConfig(newMockCallback(), "", "")
+		set := c.loadDataset(input)[0]
+		var color Color
+		if err := color.parse(set); err == nil && len(set) != 1 && len(set) != 4 {
+			t.Errorf("Color set %q of %d values parsed", input, len(set))
+		}
+	})
+}
+
+func FuzzGUIDParse(f *testing.F) {
+	f.Add("CAFE000000000000000000000000F00D")
+	f.Add("cafe000000000000000000000000f00d")
+	f.Add("CAFE000000000000000000000000F00G")
+	f.Add("é")
+	f.Fuzz(func(t *testing.T, input string) {
+		var guid GUID
+		if err := guid.parse(input); err != nil {
+			if !guid.IsZero() {
+				t.Errorf("GUID %v changed by invalid input %q", guid, input)
+			}
+			return
+		}
+		if s := guid.String(); !strings.EqualFold(s, input) {
+			t.Errorf("GUID %q parsed as %s", input, s)
+		}
+	})
+}
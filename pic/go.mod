@@ -1,3 +1,8 @@
 module github.com/PreciselyData/compose-chart-api/pic
 
-go 1.12
\ No newline at end of file
+go 1.21
+
+require (
+	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
+	golang.org/x/image v0.18.0
+)
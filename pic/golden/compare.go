@@ -0,0 +1,161 @@
+package golden
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+	"golang.org/x/image/bmp"
+)
+
+// compare compares an image with its golden image. For raster formats the
+// PNG of an image highlighting the differences is returned with the error.
+func compare(f pic.ImageFormat, got, want []byte, tol Tolerance) ([]byte, error) {
+	if f == pic.SVG {
+		return nil, compareSVG(got, want, tol.MaxDelta)
+	}
+	gotImg, err := decode(f, got)
+	if err != nil {
+		return nil, fmt.Errorf("decoding image: %w", err)
+	}
+	wantImg, err := decode(f, want)
+	if err != nil {
+		return nil, fmt.Errorf("decoding golden image: %w", err)
+	}
+	return compareRaster(gotImg, wantImg, tol)
+}
+
+func decode(f pic.ImageFormat, data []byte) (image.Image, error) {
+	r := bytes.NewReader(data)
+	switch f {
+	case pic.PNG:
+		return png.Decode(r)
+	case pic.JPG:
+		return jpeg.Decode(r)
+	case pic.BMP:
+		return bmp.Decode(r)
+	}
+	return nil, fmt.Errorf("unsupported image format %v", f)
+}
+
+// maxYIQDelta is the largest possible value of yiqDelta, between black and
+// white.
+const maxYIQDelta = 35215.0
+
+// compareRaster compares the images pixel by pixel using the perceptual
+// colour difference of pixelmatch (Kotsarenko and Ramos, "Measuring
+// perceived color difference using YIQ NTSC transmission color space in
+// mobile applications").
+func compareRaster(got, want image.Image, tol Tolerance) ([]byte, error) {
+	gb, wb := got.Bounds(), want.Bounds()
+	if gb.Dx() != wb.Dx() || gb.Dy() != wb.Dy() {
+		return nil, fmt.Errorf(
+			"image is %dx%d pixels, golden image is %dx%d",
+			gb.Dx(), gb.Dy(), wb.Dx(), wb.Dy(),
+		)
+	}
+
+	diff := image.NewNRGBA(image.Rect(0, 0, wb.Dx(), wb.Dy()))
+	maxDelta := maxYIQDelta * tol.Threshold * tol.Threshold
+	n := 0
+	for y := 0; y < wb.Dy(); y++ {
+		for x := 0; x < wb.Dx(); x++ {
+			g := got.At(gb.Min.X+x, gb.Min.Y+y)
+			w := want.At(wb.Min.X+x, wb.Min.Y+y)
+			if yiqDelta(g, w) > maxDelta {
+				diff.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+				n++
+			} else {
+				// Fade the golden image so that the differences stand out.
+				v := uint8(255 - (255-brightness(w))/10)
+				diff.SetNRGBA(x, y, color.NRGBA{R: v, G: v, B: v, A: 255})
+			}
+		}
+	}
+	total := wb.Dx() * wb.Dy()
+	if n == 0 || float64(n) <= tol.MaxDiff*float64(total) {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, diff); err != nil {
+		return nil, err
+	}
+	err := fmt.Errorf(
+		"%d of %d pixels differ (%.3f%%)",
+		n, total, 100*float64(n)/float64(total),
+	)
+	return buf.Bytes(), err
+}
+
+// blend gets the components of a colour blended with a white background.
+func blend(c color.Color) (r, g, b float64) {
+	cr, cg, cb, ca := c.RGBA()
+	white := float64(0xffff - ca)
+	scale := 255.0 / 0xffff
+	return (float64(cr) + white) * scale, (float64(cg) + white) * scale, (float64(cb) + white) * scale
+}
+
+func brightness(c color.Color) float64 {
+	r, g, b := blend(c)
+	return 0.29889531*r + 0.58662247*g + 0.11448223*b
+}
+
+// yiqDelta gets the squared perceptual difference between two colours.
+func yiqDelta(c1, c2 color.Color) float64 {
+	r1, g1, b1 := blend(c1)
+	r2, g2, b2 := blend(c2)
+	y := (0.29889531*r1 + 0.58662247*g1 + 0.11448223*b1) - (0.29889531*r2 + 0.58662247*g2 + 0.11448223*b2)
+	i := (0.59597799*r1 - 0.27417610*g1 - 0.32180189*b1) - (0.59597799*r2 - 0.27417610*g2 - 0.32180189*b2)
+	q := (0.21147017*r1 - 0.52261711*g1 + 0.31114694*b1) - (0.21147017*r2 - 0.52261711*g2 + 0.31114694*b2)
+	return 0.5053*y*y + 0.299*i*i + 0.1957*q*q
+}
+
+var svgNumber = regexp.MustCompile(`[-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?`)
+
+// compareSVG compares SVG images as text, allowing the numbers in them to
+// differ by up to maxDelta, and ignoring the indentation of each line.
+func compareSVG(got, want []byte, maxDelta float64) error {
+	gotLines := strings.Split(string(got), "\n")
+	wantLines := strings.Split(string(want), "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = strings.TrimSpace(gotLines[i])
+		}
+		if i < len(wantLines) {
+			w = strings.TrimSpace(wantLines[i])
+		}
+		if !svgLineEqual(g, w, maxDelta) {
+			return fmt.Errorf("line %d differs:\n\t%s\ngolden:\n\t%s", i+1, g, w)
+		}
+	}
+	return nil
+}
+
+func svgLineEqual(got, want string, maxDelta float64) bool {
+	if got == want {
+		return true
+	}
+	if strings.Join(svgNumber.Split(got, -1), "\x00") != strings.Join(svgNumber.Split(want, -1), "\x00") {
+		return false
+	}
+	gotNums := svgNumber.FindAllString(got, -1)
+	wantNums := svgNumber.FindAllString(want, -1)
+	for i := range gotNums {
+		g, gerr := strconv.ParseFloat(gotNums[i], 64)
+		w, werr := strconv.ParseFloat(wantNums[i], 64)
+		if gerr != nil || werr != nil || math.Abs(g-w) > maxDelta {
+			return false
+		}
+	}
+	return true
+}
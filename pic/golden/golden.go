@@ -0,0 +1,223 @@
+// Package golden tests that the charts of an engine look the same as they
+// did, so that upgrading a charting library or pic cannot silently change
+// how charts look in printed documents. Each configuration in a folder of
+// cfg files is rendered at several sizes, resolutions and formats and the
+// images compared with the golden images stored with the tests:
+//
+//	func TestGolden(t *testing.T) {
+//		s := &golden.Suite{Client: &client{}, Dir: "config"}
+//		s.Run(t)
+//	}
+//
+// Run the tests with the -golden.update flag, or set Suite.Update, to
+// create or update the golden images. When an image differs from its
+// golden image the image, and for raster formats an image highlighting the
+// differing pixels in red, are written to the diff folder.
+package golden
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+	"github.com/PreciselyData/compose-chart-api/pic/cfg"
+	"github.com/PreciselyData/compose-chart-api/pic/pictest"
+	"github.com/PreciselyData/compose-chart-api/pic/proptemplate"
+)
+
+// update is namespaced so that it does not clash with an -update flag
+// defined by the tests of the chart engine.
+var update = flag.Bool("golden.update", false, "update the golden images of the chart configurations")
+
+// Image describes an image of each configuration to be compared.
+type Image struct {
+	// Name distinguishes the golden images of a configuration. By default
+	// it is formed from the size and resolution, for example 4x3in-96dpi.
+	Name string
+	pic.ImageSpec
+}
+
+func (img Image) name() string {
+	if img.Name != "" {
+		return img.Name
+	}
+	return fmt.Sprintf("%gx%gin-%ddpi", img.Width.Inches(), img.Height.Inches(), img.DPI)
+}
+
+// DefaultImages are the images compared unless Suite.Images is set: 4 by 3
+// inches in PNG format at 96 and 300 DPI, and in SVG format.
+var DefaultImages = []Image{
+	{ImageSpec: pic.ImageSpec{Width: 4 * pic.Inch, Height: 3 * pic.Inch, DPI: 96, Format: pic.PNG, ColorSpace: pic.RGB}},
+	{ImageSpec: pic.ImageSpec{Width: 4 * pic.Inch, Height: 3 * pic.Inch, DPI: 300, Format: pic.PNG, ColorSpace: pic.RGB}},
+	{ImageSpec: pic.ImageSpec{Width: 4 * pic.Inch, Height: 3 * pic.Inch, DPI: 96, Format: pic.SVG, ColorSpace: pic.RGB}},
+}
+
+// Tolerance defines how different an image may be from its golden image.
+type Tolerance struct {
+	// Threshold is the perceptual difference in colour, from 0 to 1, above
+	// which a pixel differs from the golden pixel.
+	Threshold float64
+	// MaxDiff is the fraction of the pixels that may differ.
+	MaxDiff float64
+	// MaxDelta is the largest difference allowed between the numbers, such
+	// as coordinates, in an SVG image and its golden image.
+	MaxDelta float64
+}
+
+// DefaultTolerance is the tolerance used unless Suite.Tolerance is set. It
+// allows for the anti-aliasing differences of font rasterizers, but not for
+// changes in layout.
+var DefaultTolerance = Tolerance{Threshold: 0.1, MaxDiff: 0.001, MaxDelta: 0.01}
+
+// Suite compares the images of the configurations in a folder of cfg files
+// with their golden images.
+type Suite struct {
+	// Client creates the images. If nil, the Client registered with pic for
+	// the engine of the configuration is used.
+	Client pic.Client
+	// Dir is the folder of cfg files; see package cfg.
+	Dir string
+	// GoldenDir is the folder of the golden images, testdata/golden by
+	// default.
+	GoldenDir string
+	// DiffDir is the folder to which the images that differ are written,
+	// the diff folder in GoldenDir by default.
+	DiffDir string
+	// Images are the images of each configuration, DefaultImages by default.
+	Images []Image
+	// Tolerance is DefaultTolerance if nil.
+	Tolerance *Tolerance
+	// Options configure the pictest.Resolver used in place of
+	// Designer/Generate.
+	Options []pictest.Option
+	// Defaults expands the default colour and font tokens of the cfg
+	// files, as Designer does, for the colour and font properties of the
+	// engine's xml file in Dir. It is pic.NewDefaultsTable() if nil.
+	Defaults *pic.DefaultsTable
+	// Update creates or updates the golden images instead of comparing
+	// them, as does the -golden.update flag.
+	Update bool
+}
+
+// Run runs a subtest for each image of each configuration.
+func (s *Suite) Run(t *testing.T) {
+	t.Helper()
+	configs, err := cfg.List(s.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range configs {
+		settings, err := c.Load()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		names, err := proptemplate.ReadColorAndFontProperties(s.Dir, c.Engine)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		props, err := s.defaults().Expand(settings.String(), names...)
+		if err != nil {
+			t.Errorf("%s: %v", c.Name(), err)
+			continue
+		}
+		for _, img := range s.images() {
+			name := c.Name() + "_" + img.name()
+			t.Run(name, func(t *testing.T) {
+				if err := s.check(name, props, img.ImageSpec); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	}
+}
+
+func (s *Suite) defaults() *pic.DefaultsTable {
+	if s.Defaults != nil {
+		return s.Defaults
+	}
+	return pic.NewDefaultsTable()
+}
+
+func (s *Suite) images() []Image {
+	if len(s.Images) > 0 {
+		return s.Images
+	}
+	return DefaultImages
+}
+
+func (s *Suite) tolerance() Tolerance {
+	if s.Tolerance != nil {
+		return *s.Tolerance
+	}
+	return DefaultTolerance
+}
+
+func (s *Suite) goldenDir() string {
+	if s.GoldenDir != "" {
+		return s.GoldenDir
+	}
+	return filepath.Join("testdata", "golden")
+}
+
+func (s *Suite) diffDir() string {
+	if s.DiffDir != "" {
+		return s.DiffDir
+	}
+	return filepath.Join(s.goldenDir(), "diff")
+}
+
+// check renders an image of the configuration and compares it with its
+// golden image, or updates the golden image.
+func (s *Suite) check(name, props string, spec pic.ImageSpec) error {
+	r := pictest.NewResolver(s.Options...)
+	data, err := pic.CreateImage(s.Client, r, props, "", &spec, pic.Options{})
+	if err != nil {
+		return fmt.Errorf("return code %v: %w", pic.ErrorCode(err), err)
+	}
+	name += extension(spec.Format)
+	golden := filepath.Join(s.goldenDir(), name)
+
+	if s.Update || *update {
+		if err := os.MkdirAll(s.goldenDir(), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(golden, data, 0o644)
+	}
+
+	want, err := os.ReadFile(golden)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no golden image %s; run the tests with -golden.update to create it", golden)
+	}
+	if err != nil {
+		return err
+	}
+	diff, cerr := compare(spec.Format, data, want, s.tolerance())
+	if cerr == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(s.diffDir(), 0o755); err != nil {
+		return err
+	}
+	files := []string{filepath.Join(s.diffDir(), name)}
+	if err := os.WriteFile(files[0], data, 0o644); err != nil {
+		return err
+	}
+	if diff != nil {
+		files = append(files, filepath.Join(s.diffDir(), strings.TrimSuffix(name, filepath.Ext(name))+".diff.png"))
+		if err := os.WriteFile(files[1], diff, 0o644); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("%s: %w (see %s)", golden, cerr, strings.Join(files, ", "))
+}
+
+func extension(f pic.ImageFormat) string {
+	return "." + strings.ToLower(f.String())
+}
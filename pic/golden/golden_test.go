@@ -0,0 +1,206 @@
+package golden
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+	"github.com/PreciselyData/compose-chart-api/pic/pictest"
+)
+
+// barClient draws a single bar of the width given by the bar property, in
+// PNG or SVG format.
+type barClient struct{}
+
+func (barClient) NewBuilder(c *pic.Config) pic.Builder {
+	return &barBuilder{Config: c}
+}
+
+type barBuilder struct {
+	*pic.Config
+	format        pic.ImageFormat
+	width, height int
+}
+
+func (b *barBuilder) SetFormat(format *pic.ImageFormat, colorSpace *pic.ColorSpace) {
+	if *format != pic.SVG {
+		*format = pic.PNG
+	}
+	b.format = *format
+}
+
+func (b *barBuilder) SetSize(width, height pic.Twiplet, dpi int32) {
+	b.width, b.height = width.Pixels(dpi), height.Pixels(dpi)
+}
+
+func (b *barBuilder) Render() (*bytes.Buffer, error) {
+	bar := int(b.Number("bar") * float64(b.width) / 100)
+	buf := &bytes.Buffer{}
+	if b.format == pic.SVG {
+		fmt.Fprintf(buf, "<svg width=\"%d\" height=\"%d\">\n", b.width, b.height)
+		fmt.Fprintf(buf, "  <rect width=\"%d\" height=\"%d\" fill=\"red\"/>\n", bar, b.height)
+		fmt.Fprintln(buf, "</svg>")
+		return buf, nil
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, b.width, b.height))
+	for y := 0; y < b.height; y++ {
+		for x := 0; x < b.width; x++ {
+			c := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+			if x < bar {
+				c = color.NRGBA{R: 255, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return buf, png.Encode(buf, img)
+}
+
+func newSuite(t *testing.T) *Suite {
+	dir := t.TempDir()
+	pictest.WriteFiles(t, dir, map[string]string{
+		"bar.cfg":        "engine=bar\nbar=50\n",
+		"bar-half.cfg":   "config=half\n",
+		"bar-custom.cfg": "config=custom\nbar=25\n",
+	})
+	return &Suite{
+		Client:    barClient{},
+		Dir:       dir,
+		GoldenDir: filepath.Join(dir, "golden"),
+		Images: []Image{
+			{ImageSpec: pic.ImageSpec{Width: pic.Inch, Height: pic.Inch / 2, DPI: 100, Format: pic.PNG}},
+			{Name: "small", ImageSpec: pic.ImageSpec{Width: pic.Inch, Height: pic.Inch, DPI: 10, Format: pic.BMP}},
+			{ImageSpec: pic.ImageSpec{Width: pic.Inch, Height: pic.Inch, DPI: 96, Format: pic.SVG}},
+		},
+	}
+}
+
+func TestSuite(t *testing.T) {
+	s := newSuite(t)
+	s.Update = true
+	s.Run(t)
+	s.Update = false
+
+	for _, name := range []string{
+		"bar-custom_1x0.5in-100dpi.png", "bar-custom_small.png", "bar-custom_1x1in-96dpi.svg",
+		"bar-half_1x0.5in-100dpi.png", "bar-half_small.png", "bar-half_1x1in-96dpi.svg",
+	} {
+		if _, err := os.Stat(filepath.Join(s.GoldenDir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// The golden images match.
+	s.Run(t)
+	if _, err := os.Stat(s.diffDir()); !os.IsNotExist(err) {
+		t.Errorf("diff folder created: %v", err)
+	}
+}
+
+func TestSuiteDiff(t *testing.T) {
+	s := newSuite(t)
+	s.Update = true
+	if err := s.check("bar-custom_png", "bar=25", s.Images[0].ImageSpec); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.check("bar-custom_svg", "bar=25", s.Images[2].ImageSpec); err != nil {
+		t.Fatal(err)
+	}
+	s.Update = false
+
+	err := s.check("bar-custom_png", "bar=30", s.Images[0].ImageSpec)
+	if err == nil || !strings.Contains(err.Error(), "250 of 5000 pixels differ (5.000%)") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	diff, err := os.ReadFile(filepath.Join(s.diffDir(), "bar-custom_png.diff.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(diff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.NRGBAModel.Convert(img.At(27, 10)); c != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("Diff pixel %v, want red", c)
+	}
+	if c := color.NRGBAModel.Convert(img.At(80, 10)).(color.NRGBA); c.R != c.G {
+		t.Errorf("Unchanged pixel %v, want grey", c)
+	}
+	if _, err := os.Stat(filepath.Join(s.diffDir(), "bar-custom_png.png")); err != nil {
+		t.Error(err)
+	}
+
+	err = s.check("bar-custom_svg", "bar=30", s.Images[2].ImageSpec)
+	if err == nil || !strings.Contains(err.Error(), "line 2 differs") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.diffDir(), "bar-custom_svg.svg")); err != nil {
+		t.Error(err)
+	}
+
+	err = s.check("missing", "bar=30", s.Images[0].ImageSpec)
+	if err == nil || !strings.Contains(err.Error(), "-golden.update") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestCompareRasterTolerance(t *testing.T) {
+	newImage := func(c color.Color) *image.NRGBA {
+		img := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+		for y := 0; y < 100; y++ {
+			for x := 0; x < 100; x++ {
+				img.Set(x, y, c)
+			}
+		}
+		return img
+	}
+	want := newImage(color.White)
+
+	// A slightly different shade is not a perceptible difference.
+	got := newImage(color.NRGBA{R: 250, G: 250, B: 250, A: 255})
+	if _, err := compareRaster(got, want, DefaultTolerance); err != nil {
+		t.Error(err)
+	}
+
+	// A few anti-aliased pixels may differ.
+	got = newImage(color.White)
+	for x := 0; x < 10; x++ {
+		got.Set(x, 5, color.Black)
+	}
+	if _, err := compareRaster(got, want, DefaultTolerance); err != nil {
+		t.Error(err)
+	}
+	got.Set(10, 5, color.Black)
+	if _, err := compareRaster(got, want, DefaultTolerance); err == nil {
+		t.Error("11 differing pixels of 10000 allowed")
+	}
+
+	got = image.NewNRGBA(image.Rect(0, 0, 100, 99))
+	if _, err := compareRaster(got, want, DefaultTolerance); err == nil || err.Error() != "image is 100x99 pixels, golden image is 100x100" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestCompareSVG(t *testing.T) {
+	want := "<svg>\n  <path d=\"M 10.5 20 L 30 40\"/>\n</svg>\n"
+	for _, test := range []struct {
+		got string
+		ok  bool
+	}{
+		{want, true},
+		{"<svg>\n<path d=\"M 10.504 20 L 30 40.001\"/>\n</svg>\n", true},
+		{"<svg>\n  <path d=\"M 10.6 20 L 30 40\"/>\n</svg>\n", false},
+		{"<svg>\n  <path d=\"M 10.5 20 L 30 40 Z\"/>\n</svg>\n", false},
+		{"<svg>\n  <path d=\"M 10.5 20 L 30 40\"/>\n</svg>\n<!-- -->\n", false},
+	} {
+		if err := compareSVG([]byte(test.got), []byte(want), DefaultTolerance.MaxDelta); (err == nil) != test.ok {
+			t.Errorf("compareSVG(%q) = %v", test.got, err)
+		}
+	}
+}
@@ -15,33 +15,44 @@ func (g *GUID) IsZero() bool {
 	return true
 }
 
+// String formats the GUID as 32 hexadecimal digits, as in the configuration.
+func (g GUID) String() string {
+	return fmt.Sprintf("%X", g[:])
+}
+
+// ParseGUID parses a GUID of 32 hexadecimal digits, as in the
+// configuration.
+func ParseGUID(s string) (GUID, error) {
+	var g GUID
+	err := g.parse(s)
+	return g, err
+}
+
 func (g *GUID) parse(val string) error {
 	if len(val) != 32 {
 		return fmt.Errorf("invalid GUID format '%s'", val)
 	}
-	for i := 0; i < 16; i++ {
-		b1, err := g.charToByte(val[2*i])
-		if err != nil {
-			return err
-		}
-		b2, err := g.charToByte(val[2*i+1])
-		if err != nil {
-			return err
+	var guid GUID
+	for i := 0; i < len(val); i++ {
+		b, ok := g.charToByte(val[i])
+		if !ok {
+			return fmt.Errorf("invalid GUID char %q at offset %d in '%s'", val[i:i+1], i, val)
 		}
-		g[i] = (b1 << 4) | b2
+		guid[i/2] = guid[i/2]<<4 | b
 	}
+	*g = guid
 	return nil
 }
 
-func (GUID) charToByte(c byte) (byte, error) {
+func (GUID) charToByte(c byte) (byte, bool) {
 	if c >= '0' && c <= '9' {
-		return c - '0', nil
+		return c - '0', true
 	}
 	if c >= 'A' && c <= 'F' {
-		return c - 'A' + 0x0A, nil
+		return c - 'A' + 0x0A, true
 	}
 	if c >= 'a' && c <= 'f' {
-		return c - 'a' + 0x0a, nil
+		return c - 'a' + 0x0a, true
 	}
-	return 0, fmt.Errorf("invalid GUID char '%c'", c)
+	return 0, false
 }
@@ -0,0 +1,129 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+	"github.com/PreciselyData/compose-chart-api/pic/cfg"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// DefaultPointSize is the size of the fonts not listed in fonts.cfg.
+const DefaultPointSize = 10
+
+// fontDir finds the fonts in a folder. The index of the folder is read
+// when the first font is needed, and the font files are loaded by pic.
+type fontDir struct {
+	dir   string
+	once  sync.Once
+	fonts map[pic.GUID]pic.FontResource
+	err   error
+}
+
+func newFontDir(dir string) *fontDir {
+	return &fontDir{dir: dir}
+}
+
+var (
+	goFontOnce sync.Once
+	goFont     *truetype.Font
+)
+
+// GoFont gets a font resource for the Go Regular font at DefaultPointSize.
+func GoFont() pic.FontResource {
+	goFontOnce.Do(func() {
+		goFont, _ = truetype.Parse(goregular.TTF)
+	})
+	return pic.FontResource{Typeface: "Go", PointSize: DefaultPointSize, TruetypeFont: goFont}
+}
+
+// font gets a copy of the font resource for the GUID.
+func (d *fontDir) font(guid pic.GUID) (*pic.FontResource, error) {
+	if d.dir == "" {
+		fr := GoFont()
+		return &fr, nil
+	}
+	d.once.Do(d.load)
+	if d.err != nil {
+		return nil, d.err
+	}
+	if fr, ok := d.fonts[guid]; ok {
+		return &fr, nil
+	}
+	name := filepath.Join(d.dir, guid.String()+".ttf")
+	if _, err := os.Stat(name); err == nil {
+		return &pic.FontResource{
+			Typeface:  guid.String(),
+			PointSize: DefaultPointSize,
+			Filename:  name,
+		}, nil
+	}
+	if guid.IsZero() {
+		fr := GoFont()
+		return &fr, nil
+	}
+	return nil, fmt.Errorf("font not found for guid %v in %s", guid, d.dir)
+}
+
+// load reads the index of the folder, fonts.cfg, if there is one.
+func (d *fontDir) load() {
+	d.fonts = make(map[pic.GUID]pic.FontResource)
+	name := filepath.Join(d.dir, "fonts.cfg")
+	settings, err := cfg.Read(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		d.err = err
+		return
+	}
+	for _, s := range settings {
+		guid, err := pic.ParseGUID(s.Name)
+		var fr pic.FontResource
+		if err == nil {
+			fr, err = d.parseFont(s.Value)
+		}
+		if err != nil {
+			d.err = fmt.Errorf("%s: font %s: %w", name, s.Name, err)
+			return
+		}
+		d.fonts[guid] = fr
+	}
+}
+
+// parseFont parses <file>,<point size>[,bold][,italic].
+func (d *fontDir) parseFont(s string) (pic.FontResource, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) < 2 {
+		return pic.FontResource{}, fmt.Errorf("invalid font '%s'", s)
+	}
+	size, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+	if err != nil || size <= 0 {
+		return pic.FontResource{}, fmt.Errorf("invalid point size '%s'", fields[1])
+	}
+	file := strings.TrimSpace(fields[0])
+	fr := pic.FontResource{
+		Typeface:  strings.TrimSuffix(file, filepath.Ext(file)),
+		PointSize: size,
+		Filename:  filepath.Join(d.dir, file),
+	}
+	for _, attr := range fields[2:] {
+		switch strings.ToLower(strings.TrimSpace(attr)) {
+		case "bold":
+			fr.Attributes |= pic.Bold
+		case "italic":
+			fr.Attributes |= pic.Italic
+		default:
+			return pic.FontResource{}, fmt.Errorf("invalid font attribute '%s'", attr)
+		}
+	}
+	return fr, nil
+}
@@ -0,0 +1,217 @@
+// Package local answers the questions asked of Designer/Generate on a
+// developer's machine, so that chart engines can be run by tools such as
+// picrender without Designer or Generate. Numbers are parsed by Go in the
+// number format given, and fonts are loaded from a folder of font files.
+//
+//	r := local.NewResolver(local.WithFontDir("fonts"))
+//	data, err := pic.CreateImage(nil, r, props, "", &spec, pic.Options{})
+package local
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+)
+
+// Resolver answers the questions asked of Designer/Generate locally. Unless
+// configured otherwise it parses numbers in the default number format,
+// dates in the form M/D/YYYY and times in the form HH:MM:SS, and gives the
+// Go Regular font for every font. It is safe for concurrent use.
+type Resolver struct {
+	numberFormat   pic.NumberFormat
+	dateTimeFormat pic.DateTimeFormat
+	dateLayout     string
+	timeLayout     string
+	fonts          *fontDir
+}
+
+// Option configures a Resolver.
+type Option func(r *Resolver)
+
+// NewResolver creates a Resolver with the options.
+func NewResolver(opts ...Option) *Resolver {
+	r := &Resolver{
+		numberFormat:   pic.DefaultNumberFormat(),
+		dateTimeFormat: pic.DefaultDateTimeFormat(),
+		dateLayout:     "1/2/2006",
+		timeLayout:     "15:04:05",
+		fonts:          newFontDir(""),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// WithNumberFormat sets the number format, which is also used to parse
+// integers and numbers.
+func WithNumberFormat(nf pic.NumberFormat) Option {
+	return func(r *Resolver) { r.numberFormat = nf }
+}
+
+// WithDateTimeFormat sets the date and time format.
+func WithDateTimeFormat(dtf pic.DateTimeFormat) Option {
+	return func(r *Resolver) { r.dateTimeFormat = dtf }
+}
+
+// WithDateLayout sets the layout, in the form used by time.Parse, in which
+// dates are parsed.
+func WithDateLayout(layout string) Option {
+	return func(r *Resolver) { r.dateLayout = layout }
+}
+
+// WithTimeLayout sets the layout, in the form used by time.Parse, in which
+// times are parsed.
+func WithTimeLayout(layout string) Option {
+	return func(r *Resolver) { r.timeLayout = layout }
+}
+
+// WithFontDir sets the folder the fonts are loaded from; see FontResource.
+func WithFontDir(dir string) Option {
+	return func(r *Resolver) { r.fonts = newFontDir(dir) }
+}
+
+// ParseNumberFormat parses a number format given as its thousands separator
+// followed by its decimal point, for example ",." or ".,". A single
+// character is a decimal point without a thousands separator.
+func ParseNumberFormat(s string) (pic.NumberFormat, error) {
+	runes := []rune(s)
+	switch len(runes) {
+	case 1:
+		return pic.NumberFormat{DecimalPoint: runes[0]}, nil
+	case 2:
+		if runes[0] != runes[1] {
+			return pic.NumberFormat{ThousandsSeparator: runes[0], DecimalPoint: runes[1]}, nil
+		}
+	}
+	return pic.NumberFormat{}, fmt.Errorf("invalid number format '%s'", s)
+}
+
+// Integer parses an integer, which may contain thousands separators. A
+// typed value, as given by the configuration, is parsed as its text.
+func (r *Resolver) Integer(s string) (int32, error) {
+	s = pic.Value(s).Text()
+	i, err := strconv.ParseInt(r.normalise(s), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer format '%s'", s)
+	}
+	return int32(i), nil
+}
+
+// Number parses a number in the number format. A typed value is parsed as
+// its text.
+func (r *Resolver) Number(s string) (float64, error) {
+	s = pic.Value(s).Text()
+	n, err := strconv.ParseFloat(r.normalise(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number format '%s'", s)
+	}
+	return n, nil
+}
+
+// normalise removes thousands separators and currency symbols from a
+// number, and uses '.' as its decimal point.
+func (r *Resolver) normalise(s string) string {
+	nf := r.numberFormat
+	s = strings.TrimFunc(s, func(c rune) bool {
+		return unicode.IsSpace(c) || unicode.Is(unicode.Sc, c)
+	})
+	if nf.ThousandsSeparator != 0 {
+		s = strings.ReplaceAll(s, string(nf.ThousandsSeparator), "")
+	}
+	if nf.DecimalPoint != 0 && nf.DecimalPoint != '.' {
+		s = strings.Replace(s, string(nf.DecimalPoint), ".", 1)
+	}
+	return s
+}
+
+// Date parses a date in the date layout. A typed value is parsed as its
+// text.
+func (r *Resolver) Date(s string) (time.Time, error) {
+	s = pic.Value(s).Text()
+	d, err := time.Parse(r.dateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date format '%s'", s)
+	}
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+// TimeOfDay parses a time in the time layout. A typed value is parsed as
+// its text.
+func (r *Resolver) TimeOfDay(s string) (time.Time, error) {
+	s = pic.Value(s).Text()
+	t, err := time.Parse(r.timeLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time format '%s'", s)
+	}
+	return time.Date(0, time.January, 1, t.Hour(), t.Minute(), t.Second(), 0, time.UTC), nil
+}
+
+// DataValue converts a data value of the type.
+func (r *Resolver) DataValue(s string, t pic.DataType) (pic.Datum, error) {
+	return ConvertDataValue(r, s, t)
+}
+
+// ConvertDataValue converts a data value of the type with the Integer,
+// Number, Date or TimeOfDay method of the resolver, as Designer/Generate
+// does. A Neutral value, which is plain text, carries no data. It
+// implements DataValue for resolvers that wrap a Resolver.
+func ConvertDataValue(r pic.Resolver, s string, t pic.DataType) (pic.Datum, error) {
+	d := pic.Datum{Type: t}
+	var err error
+	switch t {
+	case pic.Neutral:
+	case pic.Integer:
+		d.Integer, err = r.Integer(s)
+	case pic.Number, pic.Currency:
+		d.Number, err = r.Number(s)
+	case pic.Date:
+		d.Time, err = r.Date(s)
+	case pic.Time:
+		d.Time, err = r.TimeOfDay(s)
+	default:
+		err = fmt.Errorf("invalid data type %v", t)
+	}
+	if err != nil {
+		return pic.Datum{Type: pic.NotSet}, err
+	}
+	return d, nil
+}
+
+// NumberFormat gets the number format.
+func (r *Resolver) NumberFormat() pic.NumberFormat {
+	return r.numberFormat
+}
+
+// DateTimeFormat gets the date and time format.
+func (r *Resolver) DateTimeFormat() pic.DateTimeFormat {
+	return r.dateTimeFormat
+}
+
+// FontResource gets the font for the GUID from the font folder. The fonts
+// are listed in the file fonts.cfg in the folder, one per line in the form
+//
+//	<GUID>=<file>,<point size>[,bold][,italic]
+//
+// where the GUID is 32 hexadecimal digits, as in the configuration, and the
+// file name is relative to the folder. A font that is not listed is loaded
+// from <GUID>.ttf at 10 points. The default font, whose GUID is all zeroes,
+// is the Go Regular font unless listed or found. Without a font folder
+// every font is the Go Regular font.
+func (r *Resolver) FontResource(guid pic.GUID) (*pic.FontResource, error) {
+	return r.fonts.font(guid)
+}
+
+// FontStyle gets the font style for the GUID, which is the font for the
+// GUID in black without underlining.
+func (r *Resolver) FontStyle(guid pic.GUID) (*pic.FontStyle, error) {
+	fr, err := r.fonts.font(guid)
+	if err != nil {
+		return nil, err
+	}
+	return &pic.FontStyle{FontResource: fr, Color: pic.DefaultColor}, nil
+}
@@ -0,0 +1,162 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestParseNumberFormat(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		want pic.NumberFormat
+	}{
+		{",.", pic.NumberFormat{ThousandsSeparator: ',', DecimalPoint: '.'}},
+		{".,", pic.NumberFormat{ThousandsSeparator: '.', DecimalPoint: ','}},
+		{"’.", pic.NumberFormat{ThousandsSeparator: '’', DecimalPoint: '.'}},
+		{",", pic.NumberFormat{DecimalPoint: ','}},
+	} {
+		nf, err := ParseNumberFormat(test.s)
+		if err != nil || nf != test.want {
+			t.Errorf("ParseNumberFormat(%q) = %v, %v, want %v", test.s, nf, err, test.want)
+		}
+	}
+	for _, s := range []string{"", "..", ",.,"} {
+		if _, err := ParseNumberFormat(s); err == nil {
+			t.Errorf("ParseNumberFormat(%q) succeeded", s)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	r := NewResolver(WithNumberFormat(pic.NumberFormat{ThousandsSeparator: '.', DecimalPoint: ','}))
+	if n, err := r.Number("€1.234,5"); err != nil || n != 1234.5 {
+		t.Errorf("Number() = %v, %v", n, err)
+	}
+	if i, err := r.Integer("1.234"); err != nil || i != 1234 {
+		t.Errorf("Integer() = %v, %v", i, err)
+	}
+	if _, err := r.Number("1,2,3"); err == nil || err.Error() != "invalid number format '1,2,3'" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := r.Integer("1,5"); err == nil {
+		t.Error("Integer(1,5) succeeded")
+	}
+	d, err := r.DataValue("\x1b$1.000,25", pic.Currency)
+	if err != nil || d.Number != 1000.25 {
+		t.Errorf("DataValue() = %v, %v", d, err)
+	}
+	if r.NumberFormat().DecimalPoint != ',' {
+		t.Errorf("NumberFormat() = %v", r.NumberFormat())
+	}
+}
+
+func TestDates(t *testing.T) {
+	r := NewResolver(WithDateLayout("2006-01-02"), WithTimeLayout("3:04PM"))
+	d, err := r.Date("2020-12-31")
+	if err != nil || !d.Equal(time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Date() = %v, %v", d, err)
+	}
+	tm, err := r.TimeOfDay("1:30PM")
+	if err != nil || tm.Hour() != 13 || tm.Minute() != 30 {
+		t.Errorf("TimeOfDay() = %v, %v", tm, err)
+	}
+	if _, err := r.DataValue("31/12/2020", pic.Date); err == nil {
+		t.Error("DataValue() of invalid date succeeded")
+	}
+}
+
+func TestTypedValues(t *testing.T) {
+	r := NewResolver()
+	if n, err := r.Number("\x1bn3.14"); err != nil || n != 3.14 {
+		t.Errorf("Number() = %v, %v", n, err)
+	}
+	if i, err := r.Integer("\x1bi42"); err != nil || i != 42 {
+		t.Errorf("Integer() = %v, %v", i, err)
+	}
+	if d, err := r.Date("\x1bd12/31/2020"); err != nil || !d.Equal(time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Date() = %v, %v", d, err)
+	}
+	if tm, err := r.TimeOfDay("\x1bt13:30:00"); err != nil || tm.Hour() != 13 || tm.Minute() != 30 {
+		t.Errorf("TimeOfDay() = %v, %v", tm, err)
+	}
+	c := pic.NewConfig(r, "num=\x1bn3.14\ncount=\x1bi42", "")
+	if n, err := c.NumberE("num"); err != nil || n != 3.14 {
+		t.Errorf("NumberE() = %v, %v", n, err)
+	}
+	if i, err := c.IntegerE("count"); err != nil || i != 42 {
+		t.Errorf("IntegerE() = %v, %v", i, err)
+	}
+	if d, err := r.DataValue("hello", pic.Neutral); err != nil || d != (pic.Datum{Type: pic.Neutral}) {
+		t.Errorf("DataValue(Neutral) = %+v, %v", d, err)
+	}
+}
+
+func TestFonts(t *testing.T) {
+	dir := t.TempDir()
+	guid := pic.GUID{0xCA, 0xFE, 14: 0xF0, 15: 0x0D}
+	other := pic.GUID{15: 1}
+	for name, data := range map[string][]byte{
+		"Go Bold.ttf":           goregular.TTF,
+		other.String() + ".ttf": goregular.TTF,
+		"fonts.cfg":             []byte(guid.String() + "=Go Bold.ttf,12.5,bold, italic\r\n"),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := NewResolver(WithFontDir(dir))
+
+	fr, err := r.FontResource(guid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := pic.FontResource{
+		Typeface:   "Go Bold",
+		PointSize:  12.5,
+		Attributes: pic.Bold | pic.Italic,
+		Filename:   filepath.Join(dir, "Go Bold.ttf"),
+	}
+	if *fr != want {
+		t.Errorf("FontResource() = %v, want %v", *fr, want)
+	}
+
+	fs, err := r.FontStyle(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs.Filename != filepath.Join(dir, other.String()+".ttf") || fs.PointSize != DefaultPointSize || fs.Color != pic.DefaultColor {
+		t.Errorf("FontStyle() = %v", fs)
+	}
+
+	if fr, err := r.FontResource(pic.GUID{}); err != nil || fr.TruetypeFont == nil {
+		t.Errorf("Default font = %v, %v", fr, err)
+	}
+	if _, err := r.FontResource(pic.GUID{1}); err == nil || !strings.Contains(err.Error(), "font not found") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestFontsIndexError(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "fonts.cfg"), []byte("CAFE=x.ttf,10\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := NewResolver(WithFontDir(dir))
+	if _, err := r.FontResource(pic.GUID{}); err == nil || !strings.Contains(err.Error(), "invalid GUID format 'CAFE'") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestGoFont(t *testing.T) {
+	r := NewResolver()
+	fr, err := r.FontResource(pic.GUID{1})
+	if err != nil || fr.TruetypeFont == nil || fr.Typeface != "Go" {
+		t.Errorf("FontResource() = %v, %v", fr, err)
+	}
+}
@@ -1,30 +1,238 @@
 package pic
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// LevelTrace is the slog level of the messages logged with LogTrace.
+const LevelTrace = slog.LevelDebug - 4
+
+// logger writes to the log file, syncing after each write so that the log
+// survives a crash of the host process. Writes are serialised so that
+// concurrent calls to EnchCreateImage cannot interleave their output.
+// The file is rotated once it grows beyond maxSize bytes.
 type logger struct {
-	f *os.File
+	mu       sync.Mutex
+	f        *os.File
+	name     string
+	size     int64
+	maxSize  int64
+	maxFiles int
+}
+
+var (
+	logOutputMu sync.Mutex // Serialises changes to logOutput.
+	logOutput   *logger    // Current log file, if any.
+
+	baseLogger atomic.Pointer[slog.Logger]
+	callID     atomic.Uint64
+)
+
+func init() {
+	baseLogger.Store(newLogger(Options{}))
 }
 
 func initLogger(o Options) {
+	var err error
 	if o.LogFileName != "" {
-		f, err := os.Create(o.LogFileName)
-		if err == nil {
-			log.SetOutput(&logger{f: f})
-			if o.LogInfo() {
-				log.Println("INFO: Initialised logger")
-			}
+		err = openLogFile(o)
+	}
+	baseLogger.Store(newLogger(o))
+	if err != nil {
+		rootLogger().Error("Failed to open log file", slog.Any("error", err))
+	}
+	rootLogger().Info("Initialised logger")
+}
+
+// openLogFile makes the log file named by the options the output of the
+// standard logger. A file that is already open is kept open. Otherwise an
+// existing file is appended to or, if rotated files are kept, rotated
+// rather than truncated so that the log of a failed run is not lost.
+func openLogFile(o Options) error {
+	name := expandLogFileName(o.LogFileName, os.Getpid(), time.Now())
+
+	logOutputMu.Lock()
+	defer logOutputMu.Unlock()
+	if l := logOutput; l != nil && l.name == name {
+		l.mu.Lock()
+		l.maxSize, l.maxFiles = o.LogMaxSize, o.LogMaxFiles
+		l.mu.Unlock()
+		return nil
+	}
+
+	l := &logger{name: name, maxSize: o.LogMaxSize, maxFiles: o.LogMaxFiles}
+	if !o.LogAppend && l.maxFiles > 0 {
+		if fi, err := os.Stat(name); err == nil && fi.Size() > 0 {
+			l.shift(l.maxFiles)
+		}
+	}
+	if err := l.open(o.LogAppend); err != nil {
+		return err
+	}
+	old := logOutput
+	logOutput = l
+	log.SetOutput(l)
+	if old != nil {
+		old.close()
+	}
+	return nil
+}
+
+// expandLogFileName replaces {pid} in the log file name with the process
+// ID and {date} with the date in the form YYYYMMDD.
+func expandLogFileName(name string, pid int, now time.Time) string {
+	return strings.NewReplacer(
+		"{pid}", strconv.Itoa(pid),
+		"{date}", now.Format("20060102"),
+	).Replace(name)
+}
+
+// newLogger creates the logger for the options. Unless a handler is given,
+// the log is written to the output of the standard logger, which is the
+// log file if one has been specified.
+func newLogger(o Options) *slog.Logger {
+	h := o.LogHandler
+	if h == nil {
+		ho := &slog.HandlerOptions{
+			Level:       slog.LevelDebug - 8,
+			ReplaceAttr: replaceLevel,
+		}
+		if o.LogFormat == LogJSON {
+			h = slog.NewJSONHandler(stdLogWriter{}, ho)
+		} else {
+			h = slog.NewTextHandler(stdLogWriter{}, ho)
+		}
+	}
+	return slog.New(&levelHandler{level: o.LogLevel.slogLevel(), Handler: h})
+}
+
+// rootLogger gets the logger for messages that do not belong to a call to
+// EnchCreateImage, each of which has its own logger.
+func rootLogger() *slog.Logger {
+	return baseLogger.Load()
+}
+
+// newCallLogger creates the logger for a call to EnchCreateImage, adding its
+// correlation ID and the configuration name to every message.
+func newCallLogger(id uint64, config string) *slog.Logger {
+	return rootLogger().With(
+		slog.Uint64("call", id),
+		slog.String("config", config),
+	)
+}
+
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok && level == LevelTrace {
+			a.Value = slog.StringValue("TRACE")
 		}
 	}
+	return a
+}
+
+// levelHandler discards messages below the LogLevel of the options.
+type levelHandler struct {
+	level slog.Level
+	slog.Handler
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level && h.Handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{level: h.level, Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{level: h.level, Handler: h.Handler.WithGroup(name)}
+}
+
+// stdLogWriter writes to the current output of the standard logger.
+type stdLogWriter struct{}
+
+func (stdLogWriter) Write(p []byte) (int, error) {
+	return log.Writer().Write(p)
+}
+
+func (l *logger) open(appendFile bool) error {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendFile {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(l.name, flag, 0666)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.f, l.size = f, fi.Size()
+	return nil
+}
+
+// rotatedName gets the name of the nth most recent rotated log file.
+func (l *logger) rotatedName(n int) string {
+	return fmt.Sprintf("%s.%d", l.name, n)
+}
+
+// shift renames the log file to name.1, name.1 to name.2 and so on,
+// removing the oldest file so that at most keep rotated files remain.
+func (l *logger) shift(keep int) {
+	os.Remove(l.rotatedName(keep))
+	for n := keep - 1; n > 0; n-- {
+		os.Rename(l.rotatedName(n), l.rotatedName(n+1))
+	}
+	os.Rename(l.name, l.rotatedName(1))
+}
+
+// rotate starts a new log file once the current one is full, always
+// keeping the full file as name.1.
+func (l *logger) rotate() {
+	l.f.Close()
+	keep := l.maxFiles
+	if keep < 1 {
+		keep = 1
+	}
+	l.shift(keep)
+	if err := l.open(false); err != nil {
+		l.f = nil
+	}
 }
 
 func (l *logger) Write(p []byte) (n int, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f != nil && l.maxSize > 0 && l.size > 0 && l.size+int64(len(p)) > l.maxSize {
+		l.rotate()
+	}
+	if l.f == nil {
+		return len(p), nil
+	}
 	n, err = l.f.Write(p)
+	l.size += int64(n)
 	if err == nil {
 		err = l.f.Sync()
 	}
 	return
 }
+
+func (l *logger) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f != nil {
+		l.f.Close()
+		l.f = nil
+	}
+}
@@ -0,0 +1,191 @@
+package pic
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var r map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Invalid log record %q: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestCallLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: LevelTrace})
+	defer SetOptions(Options{})
+	SetOptions(Options{LogLevel: LogInfo, LogHandler: h})
+	defer withClient(renderClient(func(c *Config) (*bytes.Buffer, error) {
+		c.Integer("size")
+		return bytes.NewBufferString("chart"), nil
+	}), load().options)()
+
+	buf.Reset()
+	assertEqual(t, createImage(newMockCallback(), "config=pie\nsize=x", "", testImage()), OK)
+	assertEqual(t, createImage(newMockCallback(), "config=bar", "", testImage()), OK)
+
+	calls := map[float64]string{}
+	var failed bool
+	for _, r := range logRecords(t, &buf) {
+		call, ok := r["call"].(float64)
+		if !ok {
+			continue
+		}
+		calls[call] = r["config"].(string)
+		if r["msg"] == "Conversion failed" {
+			failed = true
+			assertEqual(t, r["config"], "pie")
+			assertEqual(t, r["level"], "ERROR")
+		}
+		if r["level"] == "DEBUG" {
+			t.Errorf("Debug message logged at info level: %v", r)
+		}
+	}
+	assertEqual(t, len(calls), 2)
+	assertEqual(t, failed, true)
+}
+
+func TestTraceLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		Level:       LevelTrace,
+		ReplaceAttr: replaceLevel,
+	})
+	defer SetOptions(Options{})
+	SetOptions(Options{LogLevel: LogTrace, LogHandler: h})
+	defer withClient(renderClient(func(c *Config) (*bytes.Buffer, error) {
+		c.Integer("size")
+		return bytes.NewBufferString("chart"), nil
+	}), load().options)()
+
+	buf.Reset()
+	assertEqual(t, createImage(newMockCallback(), "config=pie\nsize=42", "", testImage()), OK)
+
+	var traced bool
+	for _, r := range logRecords(t, &buf) {
+		if r["callback"] == "integer" {
+			traced = true
+			assertEqual(t, r["level"], "TRACE")
+			assertEqual(t, r["in"], "42")
+			assertEqual(t, r["out"], float64(42))
+		}
+	}
+	assertEqual(t, traced, true)
+}
+
+func TestLogLevel(t *testing.T) {
+	assertEqual(t, LogErrors.slogLevel(), slog.LevelError)
+	assertEqual(t, LogInfo.slogLevel(), slog.LevelInfo)
+	assertEqual(t, LogDebug.slogLevel(), slog.LevelDebug)
+	assertEqual(t, LogTrace.slogLevel(), LevelTrace)
+	assertEqual(t, Options{LogLevel: LogDebug}.LogInfo(), true)
+	assertEqual(t, Options{LogLevel: LogInfo}.LogDebug(), false)
+}
+
+func closeLogOutput() {
+	logOutputMu.Lock()
+	defer logOutputMu.Unlock()
+	if logOutput != nil {
+		logOutput.close()
+		logOutput = nil
+	}
+	log.SetOutput(io.Discard)
+}
+
+func readLogFile(t *testing.T, name string) string {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	return string(b)
+}
+
+func TestExpandLogFileName(t *testing.T) {
+	now := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+	assertEqual(t, expandLogFileName("go-chart-{pid}-{date}.log", 42, now), "go-chart-42-20200304.log")
+	assertEqual(t, expandLogFileName("go-chart.log", 42, now), "go-chart.log")
+}
+
+func TestLogAppend(t *testing.T) {
+	defer closeLogOutput()
+	name := filepath.Join(t.TempDir(), "test.log")
+	os.WriteFile(name, []byte("previous run\n"), 0666)
+
+	if err := openLogFile(Options{LogFileName: name, LogAppend: true}); err != nil {
+		t.Fatal(err)
+	}
+	log.Print("this run")
+	closeLogOutput()
+
+	content := readLogFile(t, name)
+	assertEqual(t, strings.HasPrefix(content, "previous run\n"), true)
+	assertEqual(t, strings.HasSuffix(content, "this run\n"), true)
+}
+
+func TestLogRotateOnOpen(t *testing.T) {
+	defer closeLogOutput()
+	name := filepath.Join(t.TempDir(), "test.log")
+	os.WriteFile(name, []byte("failed run\n"), 0666)
+
+	if err := openLogFile(Options{LogFileName: name, LogMaxFiles: 2}); err != nil {
+		t.Fatal(err)
+	}
+	closeLogOutput()
+
+	assertEqual(t, readLogFile(t, name), "")
+	assertEqual(t, readLogFile(t, name+".1"), "failed run\n")
+}
+
+func TestLogRotate(t *testing.T) {
+	defer closeLogOutput()
+	name := filepath.Join(t.TempDir(), "test.log")
+
+	if err := openLogFile(Options{LogFileName: name, LogMaxSize: 10, LogMaxFiles: 2}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		logOutput.Write([]byte(line))
+	}
+	closeLogOutput()
+
+	assertEqual(t, readLogFile(t, name), "fourth\n")
+	assertEqual(t, readLogFile(t, name+".1"), "third\n")
+	assertEqual(t, readLogFile(t, name+".2"), "second\n")
+	if _, err := os.Stat(name + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Too many rotated log files kept")
+	}
+}
+
+func TestLogReopen(t *testing.T) {
+	defer closeLogOutput()
+	name := filepath.Join(t.TempDir(), "test.log")
+
+	if err := openLogFile(Options{LogFileName: name}); err != nil {
+		t.Fatal(err)
+	}
+	log.Print("kept")
+	if err := openLogFile(Options{LogFileName: name}); err != nil {
+		t.Fatal(err)
+	}
+	closeLogOutput()
+
+	assertEqual(t, strings.HasSuffix(readLogFile(t, name), "kept\n"), true)
+}
@@ -0,0 +1,252 @@
+package pic
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricsFormat specifies the format of the metrics file.
+type MetricsFormat int
+
+// MetricsFormat enumeration.
+const (
+	MetricsPrometheus MetricsFormat = iota // Prometheus text exposition format.
+	MetricsJSON
+)
+
+// renderBuckets are the upper bounds in seconds of the render time histogram.
+var renderBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// configMetrics are the metrics of the charts of one configuration.
+type configMetrics struct {
+	Calls       uint64
+	ReturnCodes map[string]uint64
+	Seconds     float64
+	MinSeconds  float64
+	MaxSeconds  float64
+	Buckets     []uint64 // Calls taking at most each of renderBuckets.
+	Bytes       uint64
+	MaxBytes    uint64
+}
+
+// metrics records the outcome of every call to EnchCreateImage, by
+// configuration name.
+type metrics struct {
+	mu      sync.Mutex
+	start   time.Time
+	configs map[string]*configMetrics
+}
+
+var renderMetrics = newMetrics()
+
+func newMetrics() *metrics {
+	return &metrics{start: time.Now(), configs: make(map[string]*configMetrics)}
+}
+
+func (m *metrics) record(config string, rc ReturnCode, d time.Duration, size int) {
+	secs := d.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cm, ok := m.configs[config]
+	if !ok {
+		cm = &configMetrics{
+			ReturnCodes: make(map[string]uint64),
+			MinSeconds:  secs,
+			Buckets:     make([]uint64, len(renderBuckets)),
+		}
+		m.configs[config] = cm
+	}
+	cm.Calls++
+	cm.ReturnCodes[rc.String()]++
+	cm.Seconds += secs
+	if secs < cm.MinSeconds {
+		cm.MinSeconds = secs
+	}
+	if secs > cm.MaxSeconds {
+		cm.MaxSeconds = secs
+	}
+	for i, le := range renderBuckets {
+		if secs <= le {
+			cm.Buckets[i]++
+		}
+	}
+	cm.Bytes += uint64(size)
+	if uint64(size) > cm.MaxBytes {
+		cm.MaxBytes = uint64(size)
+	}
+}
+
+// snapshot gets a copy of the metrics and the configuration names in order.
+func (m *metrics) snapshot() (map[string]configMetrics, []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	configs := make(map[string]configMetrics, len(m.configs))
+	names := make([]string, 0, len(m.configs))
+	for name, cm := range m.configs {
+		c := *cm
+		c.ReturnCodes = make(map[string]uint64, len(cm.ReturnCodes))
+		for rc, n := range cm.ReturnCodes {
+			c.ReturnCodes[rc] = n
+		}
+		c.Buckets = append([]uint64(nil), cm.Buckets...)
+		configs[name] = c
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return configs, names
+}
+
+func (m *metrics) write(w io.Writer, f MetricsFormat) error {
+	if f == MetricsJSON {
+		return m.writeJSON(w)
+	}
+	return m.writePrometheus(w)
+}
+
+func (m *metrics) writeJSON(w io.Writer) error {
+	configs, _ := m.snapshot()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Start          time.Time
+		Time           time.Time
+		BucketSeconds  []float64
+		Configurations map[string]configMetrics
+	}{m.start, time.Now(), renderBuckets, configs})
+}
+
+func (m *metrics) writePrometheus(w io.Writer) error {
+	configs, names := m.snapshot()
+	bw := bufio.NewWriter(w)
+	metric := func(name, typ, help string) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	metric("pic_charts_total", "counter", "Charts created, by configuration and return code.")
+	for _, name := range names {
+		rcs := make([]string, 0, len(configs[name].ReturnCodes))
+		for rc := range configs[name].ReturnCodes {
+			rcs = append(rcs, rc)
+		}
+		sort.Strings(rcs)
+		for _, rc := range rcs {
+			fmt.Fprintf(bw, "pic_charts_total{config=%s,rc=%s} %d\n",
+				promLabel(name), promLabel(rc), configs[name].ReturnCodes[rc])
+		}
+	}
+
+	metric("pic_render_seconds", "histogram", "Time taken to create charts.")
+	for _, name := range names {
+		cm := configs[name]
+		for i, le := range renderBuckets {
+			fmt.Fprintf(bw, "pic_render_seconds_bucket{config=%s,le=\"%g\"} %d\n",
+				promLabel(name), le, cm.Buckets[i])
+		}
+		fmt.Fprintf(bw, "pic_render_seconds_bucket{config=%s,le=\"+Inf\"} %d\n", promLabel(name), cm.Calls)
+		fmt.Fprintf(bw, "pic_render_seconds_sum{config=%s} %g\n", promLabel(name), cm.Seconds)
+		fmt.Fprintf(bw, "pic_render_seconds_count{config=%s} %d\n", promLabel(name), cm.Calls)
+	}
+
+	metric("pic_render_seconds_max", "gauge", "Longest time taken to create a chart.")
+	for _, name := range names {
+		fmt.Fprintf(bw, "pic_render_seconds_max{config=%s} %g\n", promLabel(name), configs[name].MaxSeconds)
+	}
+
+	metric("pic_image_bytes_total", "counter", "Size of the chart images created.")
+	for _, name := range names {
+		fmt.Fprintf(bw, "pic_image_bytes_total{config=%s} %d\n", promLabel(name), configs[name].Bytes)
+	}
+
+	metric("pic_image_bytes_max", "gauge", "Size of the largest chart image created.")
+	for _, name := range names {
+		fmt.Fprintf(bw, "pic_image_bytes_max{config=%s} %d\n", promLabel(name), configs[name].MaxBytes)
+	}
+
+	metric("pic_start_time_seconds", "gauge", "Time at which the metrics started.")
+	fmt.Fprintf(bw, "pic_start_time_seconds %d\n", m.start.Unix())
+	return bw.Flush()
+}
+
+// promLabel quotes a Prometheus label value.
+func promLabel(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// writeMetricsFile replaces the metrics file named by the options. The file
+// is written under a temporary name first so that a reader never sees a
+// partial file.
+func writeMetricsFile(o Options) error {
+	if o.MetricsFileName == "" {
+		return nil
+	}
+	name := expandLogFileName(o.MetricsFileName, os.Getpid(), time.Now())
+	tmp := name + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := renderMetrics.write(f, o.MetricsFormat); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+var (
+	metricsMu   sync.Mutex    // Serialises changes to metricsStop.
+	metricsStop chan struct{} // Stops the periodic writing of metrics.
+)
+
+// startMetrics writes the metrics file every MetricsInterval, replacing any
+// previous schedule.
+func startMetrics(o Options) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	if metricsStop != nil {
+		close(metricsStop)
+		metricsStop = nil
+	}
+	if o.MetricsFileName == "" || o.MetricsInterval <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	metricsStop = stop
+	go func() {
+		t := time.NewTicker(o.MetricsInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				logMetricsError(writeMetricsFile(o))
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopMetrics stops the periodic writing of metrics and writes them a final
+// time.
+func stopMetrics(o Options) {
+	startMetrics(Options{})
+	logMetricsError(writeMetricsFile(o))
+}
+
+func logMetricsError(err error) {
+	if err != nil {
+		rootLogger().Error("Failed to write metrics file", slog.Any("error", err))
+	}
+}
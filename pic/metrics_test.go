@@ -0,0 +1,106 @@
+package pic
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsPrometheus(t *testing.T) {
+	m := newMetrics()
+	m.record("pie", OK, 20*time.Millisecond, 100)
+	m.record("pie", OK, 2*time.Second, 300)
+	m.record("pie", Failed, time.Millisecond, 0)
+	m.record(`bar "3d"`, OK, time.Minute, 50)
+
+	var buf bytes.Buffer
+	if err := m.write(&buf, MetricsPrometheus); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, line := range []string{
+		"# TYPE pic_charts_total counter",
+		`pic_charts_total{config="pie",rc="OK"} 2`,
+		`pic_charts_total{config="pie",rc="Failed"} 1`,
+		`pic_charts_total{config="bar \"3d\"",rc="OK"} 1`,
+		`pic_render_seconds_bucket{config="pie",le="0.01"} 1`,
+		`pic_render_seconds_bucket{config="pie",le="0.025"} 2`,
+		`pic_render_seconds_bucket{config="pie",le="2.5"} 3`,
+		`pic_render_seconds_bucket{config="pie",le="+Inf"} 3`,
+		`pic_render_seconds_bucket{config="bar \"3d\"",le="30"} 0`,
+		`pic_render_seconds_count{config="pie"} 3`,
+		`pic_render_seconds_max{config="bar \"3d\""} 60`,
+		`pic_image_bytes_total{config="pie"} 400`,
+		`pic_image_bytes_max{config="pie"} 300`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Missing line %q in:\n%s", line, out)
+		}
+	}
+}
+
+func TestMetricsJSON(t *testing.T) {
+	m := newMetrics()
+	m.record("pie", OK, 20*time.Millisecond, 100)
+	m.record("pie", InvalidValue, 10*time.Millisecond, 0)
+
+	var buf bytes.Buffer
+	if err := m.write(&buf, MetricsJSON); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Configurations map[string]configMetrics
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	pie := out.Configurations["pie"]
+	assertEqual(t, pie.Calls, uint64(2))
+	assertEqual(t, pie.ReturnCodes["OK"], uint64(1))
+	assertEqual(t, pie.ReturnCodes["InvalidValue"], uint64(1))
+	assertEqual(t, pie.MinSeconds, 0.01)
+	assertEqual(t, pie.MaxSeconds, 0.02)
+	assertEqual(t, pie.MaxBytes, uint64(100))
+}
+
+func TestMetricsOnTerminate(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "metrics-{pid}.prom")
+	defer withClient(renderClient(func(c *Config) (*bytes.Buffer, error) {
+		return bytes.NewBufferString("chart"), nil
+	}), Options{MetricsFileName: name})()
+
+	img := testImage()
+	assertEqual(t, createImage(newMockCallback(), "config=metrics-test", "", img), OK)
+	destroyImage(img)
+	assertEqual(t, terminate(), Failed)
+
+	b, err := os.ReadFile(expandLogFileName(name, os.Getpid(), time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `pic_charts_total{config="metrics-test",rc="OK"} 1`) {
+		t.Errorf("Chart not counted:\n%s", b)
+	}
+}
+
+func TestMetricsInterval(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "metrics.json")
+	o := Options{MetricsFileName: name, MetricsFormat: MetricsJSON, MetricsInterval: 10 * time.Millisecond}
+	startMetrics(o)
+	defer startMetrics(Options{})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(name); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Metrics file not written")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
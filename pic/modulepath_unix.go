@@ -0,0 +1,26 @@
+//go:build !windows
+
+package pic
+
+// #cgo linux LDFLAGS: -ldl
+// #define _GNU_SOURCE
+// #include <dlfcn.h>
+//
+// static void picModuleAnchor(void) {}
+//
+// static const char *picModulePath(void) {
+//     Dl_info info;
+//     if (dladdr((void *)picModuleAnchor, &info) == 0) {
+//         return NULL;
+//     }
+//     return info.dli_fname;
+// }
+import "C"
+
+// modulePath gets the path of the shared library containing pic.
+func modulePath() string {
+	if p := C.picModulePath(); p != nil {
+		return C.GoString(p)
+	}
+	return ""
+}
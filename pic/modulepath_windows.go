@@ -0,0 +1,32 @@
+package pic
+
+// #include <windows.h>
+//
+// static void picModuleAnchor(void) {}
+//
+// static DWORD picModulePath(wchar_t *buf, DWORD size) {
+//     HMODULE module;
+//     if (!GetModuleHandleExW(
+//             GET_MODULE_HANDLE_EX_FLAG_FROM_ADDRESS |
+//                 GET_MODULE_HANDLE_EX_FLAG_UNCHANGED_REFCOUNT,
+//             (LPCWSTR)picModuleAnchor, &module)) {
+//         return 0;
+//     }
+//     return GetModuleFileNameW(module, buf, size);
+// }
+import "C"
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// modulePath gets the path of the DLL containing pic.
+func modulePath() string {
+	buf := make([]uint16, syscall.MAX_LONG_PATH)
+	n := C.picModulePath((*C.wchar_t)(unsafe.Pointer(&buf[0])), C.DWORD(len(buf)))
+	if n == 0 || int(n) >= len(buf) {
+		return ""
+	}
+	return syscall.UTF16ToString(buf[:n])
+}
@@ -1,23 +1,142 @@
 package pic
 
+import (
+	"log/slog"
+	"time"
+)
+
 // LogLevel specifies which information will be logged.
 type LogLevel int
 
-// LogLevel enumeration.
+// LogLevel enumeration. Each level includes the levels before it.
 const (
 	LogErrors LogLevel = iota
 	LogInfo
+	LogDebug // Adds font resolution and render details.
+	LogTrace // Adds every call back into Designer/Generate.
+)
+
+// LogFormat specifies the format of the log file.
+type LogFormat int
+
+// LogFormat enumeration.
+const (
+	LogText LogFormat = iota // Lines of key=value pairs.
+	LogJSON                  // Lines of JSON objects.
 )
 
 // Options supplied by the client of the API.
 type Options struct {
 	LogLevel
+	LogFormat LogFormat
+
+	// LogFileName is the name of the log file. Any {pid} in the name is
+	// replaced by the process ID, and any {date} by the date the file is
+	// opened in the form YYYYMMDD, so that processes running in the same
+	// folder each have their own log file.
 	LogFileName string
-}
 
-var options Options
+	// LogAppend causes an existing log file to be appended to. Otherwise
+	// it is replaced, after being rotated if LogMaxFiles is set.
+	LogAppend bool
+
+	// LogMaxSize is the size in bytes beyond which the log file is
+	// rotated: it is renamed with the suffix .1, any previous .1 file
+	// becomes .2 and so on. Zero means no limit.
+	LogMaxSize int64
+
+	// LogMaxFiles is the number of rotated log files kept. At least one
+	// is kept when the log file is rotated because of LogMaxSize.
+	LogMaxFiles int
+
+	// LogHandler receives the log records instead of the log file, for
+	// example to forward them to a logging service. Records below the
+	// LogLevel are not passed to the handler.
+	LogHandler slog.Handler
+
+	// Strict causes EnchCreateImage to fail if a value could not be
+	// converted while creating the image, rather than drawing the chart
+	// with fallback values. Data values that cannot be converted cause
+	// InvalidDataString to be returned, and invalid colour or font
+	// values cause InvalidValue to be returned.
+	Strict bool
+
+	// RenderTimeout is the maximum time allowed to render a chart image.
+	// If it elapses, EnchCreateImage returns Failed and the builder is
+	// abandoned; a ContextBuilder is also told to stop via its context.
+	// Zero means no limit.
+	//
+	// With a timeout the builder runs on a goroutine of its own, so the
+	// callbacks to Designer/Generate, such as those resolving fonts and
+	// data values, are made from a different OS thread than the one that
+	// called EnchCreateImage. Without a timeout they are made from the
+	// calling thread. Set a timeout only for hosts that allow callbacks
+	// from other threads.
+	RenderTimeout time.Duration
+
+	// FontCacheSize is the maximum size in bytes of the font files whose
+	// parsed fonts are cached for use by all charts. Zero means
+	// DefaultFontCacheSize, and a negative size disables the cache.
+	FontCacheSize int64
+
+	// Redact specifies the customer data to be kept out of the log, capture
+	// files and crash reports.
+	Redact Redaction
+
+	// CaptureDir is the folder in which a capture file is written for each
+	// call to EnchCreateImage. The file records everything needed to create
+	// the image again without Designer/Generate; see Capture. Empty means
+	// calls are not captured.
+	CaptureDir string
+
+	// CrashDir is the folder in which a crash report is written when pic
+	// recovers from a panic in the Builder, a callback or pic itself. Empty
+	// means the folder of the log file, or the temporary folder if there
+	// is no log file.
+	CrashDir string
+
+	// CrashReportConfig causes crash reports to include the properties and
+	// symbols of the chart, which may contain customer data.
+	CrashReportConfig bool
+
+	// MetricsFileName is the name of the file to which the number of charts
+	// created, the time taken, the image sizes and the return codes are
+	// written, by configuration name, when Generate calls EnchTerminate.
+	// {pid} and {date} are replaced as for LogFileName. Empty means the
+	// metrics are not written.
+	MetricsFileName string
+	MetricsFormat   MetricsFormat
+
+	// MetricsInterval is the interval at which the metrics file is also
+	// written while charts are being created. Zero means the file is only
+	// written by EnchTerminate.
+	MetricsInterval time.Duration
+}
 
 // LogInfo determines whether info level logging is enabled.
 func (o Options) LogInfo() bool {
-	return o.LogLevel == LogInfo
+	return o.LogLevel >= LogInfo
+}
+
+// LogDebug determines whether debug level logging is enabled.
+func (o Options) LogDebug() bool {
+	return o.LogLevel >= LogDebug
+}
+
+// LogTrace determines whether trace level logging is enabled.
+func (o Options) LogTrace() bool {
+	return o.LogLevel >= LogTrace
+}
+
+func (l LogLevel) slogLevel() slog.Level {
+	switch {
+	case l <= LogErrors:
+		return slog.LevelError
+	case l == LogInfo:
+		return slog.LevelInfo
+	case l == LogDebug:
+		return slog.LevelDebug
+	default:
+		return LevelTrace
+	}
 }
@@ -0,0 +1,194 @@
+package pic
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OptionsEnvVar is the environment variable holding option overrides as a
+// list of name=value pairs separated by semicolons, for example
+//
+//	PIC_OPTIONS=logLevel=debug;logFile=go-chart-{pid}.log
+//
+// Overrides may also be kept in a sidecar file next to the shared library,
+// with the same name as the library and the extension .ini, holding one
+// name=value pair per line. Lines starting with ';' or '#' are comments.
+// The sidecar file is merged over the Options passed to SetClient or
+// SetOptions, and the environment variable is merged over both.
+//
+// The names are logLevel (errors, info, debug or trace), logFormat (text
+// or json), logFile, logAppend, logMaxSize, logMaxFiles, strict,
+// renderTimeout (for example 30s), fontCacheSize, redactSymbols,
+// redactAllowProperties (a comma-separated list), redactHash, captureDir,
+// crashDir, crashReportConfig, metricsFile, metricsFormat (prometheus or
+// json) and metricsInterval.
+const OptionsEnvVar = "PIC_OPTIONS"
+
+// override is a source of option overrides.
+type override struct {
+	source   string
+	settings map[string]string
+}
+
+// overrideOptions merges the sidecar file and environment variable over
+// the options, returning the sources used and any invalid overrides.
+func overrideOptions(o Options) (Options, []override, []error) {
+	var used []override
+	var errs []error
+	for _, ov := range loadOverrides() {
+		var err []error
+		o, err = ov.apply(o)
+		used = append(used, ov)
+		errs = append(errs, err...)
+	}
+	return o, used, errs
+}
+
+func loadOverrides() []override {
+	var ovs []override
+	if path := sidecarPath(modulePath()); path != "" {
+		if b, err := os.ReadFile(path); err == nil {
+			ovs = append(ovs, override{path, parseOverrides(string(b), '\n')})
+		}
+	}
+	if env, ok := os.LookupEnv(OptionsEnvVar); ok {
+		ovs = append(ovs, override{OptionsEnvVar, parseOverrides(env, ';')})
+	}
+	return ovs
+}
+
+// sidecarPath gets the name of the sidecar file of the shared library.
+func sidecarPath(module string) string {
+	if module == "" {
+		return ""
+	}
+	return strings.TrimSuffix(module, filepath.Ext(module)) + ".ini"
+}
+
+func parseOverrides(input string, sep byte) map[string]string {
+	out := make(map[string]string)
+	for name, value := range loadSettings(input, sep) {
+		name = strings.TrimSpace(name)
+		if name == "" || name[0] == ';' || name[0] == '#' {
+			continue
+		}
+		out[name] = strings.TrimSpace(value)
+	}
+	return out
+}
+
+func (ov override) apply(o Options) (Options, []error) {
+	var errs []error
+	for name, value := range ov.settings {
+		// Each setting is applied to a copy, kept only if it is valid.
+		n := o
+		var err error
+		switch name {
+		case "logLevel":
+			n.LogLevel, err = parseLogLevel(value)
+		case "logFormat":
+			n.LogFormat, err = parseLogFormat(value)
+		case "logFile":
+			n.LogFileName = value
+		case "logAppend":
+			n.LogAppend, err = strconv.ParseBool(value)
+		case "logMaxSize":
+			n.LogMaxSize, err = strconv.ParseInt(value, 10, 64)
+		case "logMaxFiles":
+			n.LogMaxFiles, err = strconv.Atoi(value)
+		case "strict":
+			n.Strict, err = strconv.ParseBool(value)
+		case "renderTimeout":
+			n.RenderTimeout, err = time.ParseDuration(value)
+		case "fontCacheSize":
+			n.FontCacheSize, err = strconv.ParseInt(value, 10, 64)
+		case "redactSymbols":
+			n.Redact.Symbols, err = strconv.ParseBool(value)
+		case "redactAllowProperties":
+			n.Redact.AllowProperties = splitList(value)
+		case "redactHash":
+			n.Redact.Hash, err = strconv.ParseBool(value)
+		case "captureDir":
+			n.CaptureDir = value
+		case "crashDir":
+			n.CrashDir = value
+		case "crashReportConfig":
+			n.CrashReportConfig, err = strconv.ParseBool(value)
+		case "metricsFile":
+			n.MetricsFileName = value
+		case "metricsFormat":
+			n.MetricsFormat, err = parseMetricsFormat(value)
+		case "metricsInterval":
+			n.MetricsInterval, err = time.ParseDuration(value)
+		default:
+			err = fmt.Errorf("unknown option")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: option '%s=%s': %w", ov.source, name, value, err))
+			continue
+		}
+		o = n
+	}
+	return o, errs
+}
+
+// splitList splits a comma-separated list, which may be empty.
+func splitList(s string) []string {
+	list := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func parseLogLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(s) {
+	case "errors", "error":
+		return LogErrors, nil
+	case "info":
+		return LogInfo, nil
+	case "debug":
+		return LogDebug, nil
+	case "trace":
+		return LogTrace, nil
+	}
+	return LogErrors, fmt.Errorf("invalid log level")
+}
+
+func parseLogFormat(s string) (LogFormat, error) {
+	switch strings.ToLower(s) {
+	case "text":
+		return LogText, nil
+	case "json":
+		return LogJSON, nil
+	}
+	return LogText, fmt.Errorf("invalid log format")
+}
+
+func parseMetricsFormat(s string) (MetricsFormat, error) {
+	switch strings.ToLower(s) {
+	case "prometheus":
+		return MetricsPrometheus, nil
+	case "json":
+		return MetricsJSON, nil
+	}
+	return MetricsPrometheus, fmt.Errorf("invalid metrics format")
+}
+
+// logOverrides logs the overrides once the logger reflects them.
+func logOverrides(used []override, errs []error) {
+	l := rootLogger()
+	for _, ov := range used {
+		l.Info("Applied option overrides", slog.String("source", ov.source), slog.Any("options", ov.settings))
+	}
+	for _, err := range errs {
+		l.Error("Invalid option override", slog.Any("error", err))
+	}
+}
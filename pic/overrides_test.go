@@ -0,0 +1,73 @@
+package pic
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseOverrides(t *testing.T) {
+	settings := parseOverrides("[pic]\r\n; logLevel=info\r\nlogLevel = debug\r\n# strict=true\r\nstrict=true\r\n", '\n')
+	assertEqual(t, len(settings), 2)
+	assertEqual(t, settings["logLevel"], "debug")
+	assertEqual(t, settings["strict"], "true")
+
+	settings = parseOverrides("logLevel=trace;renderTimeout=5s", ';')
+	assertEqual(t, len(settings), 2)
+	assertEqual(t, settings["renderTimeout"], "5s")
+}
+
+func TestApplyOverrides(t *testing.T) {
+	ov := override{
+		source: "test",
+		settings: parseOverrides(
+			"logLevel=trace;logFormat=JSON;logFile=pic-{pid}.log;logAppend=1;"+
+				"logMaxSize=1024;logMaxFiles=3;strict=true;renderTimeout=1m;fontCacheSize=0",
+			';',
+		),
+	}
+	o, errs := ov.apply(Options{LogLevel: LogInfo, LogFileName: "pic.log", FontCacheSize: 1})
+	assertEqual(t, len(errs), 0)
+	assertEqual(t, o.LogLevel, LogTrace)
+	assertEqual(t, o.LogFormat, LogJSON)
+	assertEqual(t, o.LogFileName, "pic-{pid}.log")
+	assertEqual(t, o.LogAppend, true)
+	assertEqual(t, o.LogMaxSize, int64(1024))
+	assertEqual(t, o.LogMaxFiles, 3)
+	assertEqual(t, o.Strict, true)
+	assertEqual(t, o.RenderTimeout, time.Minute)
+	assertEqual(t, o.FontCacheSize, int64(0))
+}
+
+func TestApplyOverridesErrors(t *testing.T) {
+	ov := override{
+		source:   "test",
+		settings: parseOverrides("logLevel=loud;strict=maybe;colour=red", ';'),
+	}
+	o, errs := ov.apply(Options{LogLevel: LogInfo, Strict: true})
+	assertEqual(t, len(errs), 3)
+	assertEqual(t, o.LogLevel, LogInfo)
+	assertEqual(t, o.Strict, true)
+	for _, err := range errs {
+		assertEqual(t, strings.HasPrefix(err.Error(), "test: option '"), true)
+	}
+}
+
+func TestOptionsEnvVar(t *testing.T) {
+	defer SetOptions(Options{})
+	t.Setenv(OptionsEnvVar, "strict=true;renderTimeout=2s")
+	SetOptions(Options{LogLevel: LogInfo, RenderTimeout: time.Second})
+	o := load().options
+	assertEqual(t, o.LogLevel, LogInfo)
+	assertEqual(t, o.Strict, true)
+	assertEqual(t, o.RenderTimeout, 2*time.Second)
+}
+
+func TestSidecarPath(t *testing.T) {
+	assertEqual(t, sidecarPath("/opt/doc1/go-chart.so"), "/opt/doc1/go-chart.ini")
+	assertEqual(t, sidecarPath(`C:\Designer\go-chart.dll`), `C:\Designer\go-chart.ini`)
+	assertEqual(t, sidecarPath(""), "")
+	if modulePath() == "" {
+		t.Errorf("Module path not found")
+	}
+}
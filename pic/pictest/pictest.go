@@ -0,0 +1,83 @@
+// Package pictest helps test chart engines without Designer/Generate. It
+// creates a *pic.Config from properties and symbols, with a fake resolver
+// standing in for Designer/Generate, and checks the outcome of creating a
+// chart image.
+//
+//	c := pictest.NewConfig("config=pie\ntitle=Sales", "",
+//		pictest.WithNumberFormat(pic.NumberFormat{ThousandsSeparator: '.', DecimalPoint: ','}))
+//	b := newBuilder(c)
+package pictest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+)
+
+// NewConfig creates the configuration of a chart from properties and
+// symbols in the form passed to EnchCreateImage, one name=value pair per
+// line, with a Resolver created with the options.
+func NewConfig(props, symbols string, opts ...Option) *pic.Config {
+	return pic.NewConfig(NewResolver(opts...), props, symbols)
+}
+
+// DefaultImage is the image required by CreateImage unless WithImage is
+// used: 4 by 3 inches at 96 DPI in PNG format.
+var DefaultImage = pic.ImageSpec{
+	Width:      4 * pic.Twiplet(pic.Inch),
+	Height:     3 * pic.Twiplet(pic.Inch),
+	DPI:        96,
+	Format:     pic.PNG,
+	ColorSpace: pic.RGB,
+}
+
+// CreateImage creates a chart image with the client in the same way as
+// EnchCreateImage, returning the image data and the ReturnCode that
+// EnchCreateImage would return. The options of the Resolver also specify
+// the image required and the pic.Options used.
+func CreateImage(c pic.Client, props, symbols string, opts ...Option) ([]byte, pic.ReturnCode) {
+	r := NewResolver(opts...)
+	spec := r.image
+	data, err := pic.CreateImage(c, r, props, symbols, &spec, r.options)
+	return data, pic.ErrorCode(err)
+}
+
+// AssertReturnCode fails the test unless the error maps to the ReturnCode,
+// as it would when returned to Designer/Generate.
+func AssertReturnCode(t testing.TB, err error, want pic.ReturnCode) {
+	t.Helper()
+	if rc := pic.ErrorCode(err); rc != want {
+		t.Errorf("Return code %v, want %v (error: %v)", rc, want, err)
+	}
+}
+
+// AssertOK fails the test if the ReturnCode is not OK.
+func AssertOK(t testing.TB, rc pic.ReturnCode) {
+	t.Helper()
+	if rc != pic.OK {
+		t.Errorf("Return code %v, want OK", rc)
+	}
+}
+
+// AssertNoFailure fails the test if any conversion of the configuration
+// failed, which would make EnchCreateImage fail in strict mode.
+func AssertNoFailure(t testing.TB, c *pic.Config) {
+	t.Helper()
+	if err := c.Err(); err != nil {
+		t.Errorf("Configuration failure: %v", err)
+	}
+}
+
+// WriteFiles writes files, such as cfg and xml files, to the folder,
+// failing the test if any cannot be written. The map is of file names to
+// their contents.
+func WriteFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
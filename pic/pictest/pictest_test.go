@@ -0,0 +1,147 @@
+package pictest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+)
+
+func TestNewConfig(t *testing.T) {
+	c := NewConfig(
+		"config=pie\nsize=1.234\nratio=1.234,5\nwhen=17/10/2026\ntotal=\x10total",
+		"total=\x1b$€1.234,50",
+		WithNumberFormat(pic.NumberFormat{ThousandsSeparator: '.', DecimalPoint: ','}),
+		WithDateLayout("2/1/2006"),
+	)
+	if got := c.Name(); got != "pie" {
+		t.Errorf("Name() = %q", got)
+	}
+	if got := c.Integer("size"); got != 1234 {
+		t.Errorf("Integer(size) = %v", got)
+	}
+	if got := c.Number("ratio"); got != 1234.5 {
+		t.Errorf("Number(ratio) = %v", got)
+	}
+	if got := c.Date("when"); !got.Equal(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Date(when) = %v", got)
+	}
+	d, err := c.Resolve(c.Value("total"))
+	if err != nil || d.Type != pic.Currency || d.Number != 1234.5 {
+		t.Errorf("Resolve(total) = %+v, %v", d, err)
+	}
+	if got := c.NumberFormat().DecimalPoint; got != ',' {
+		t.Errorf("NumberFormat().DecimalPoint = %q", got)
+	}
+	AssertNoFailure(t, c)
+}
+
+func TestResolverAnswers(t *testing.T) {
+	r := NewResolver(WithNumber("lots", 1e6), WithError("bad", errors.New("bad value")))
+	if n, err := r.Number("lots"); n != 1e6 || err != nil {
+		t.Errorf("Number(lots) = %v, %v", n, err)
+	}
+	if _, err := r.Integer("bad"); err == nil || err.Error() != "bad value" {
+		t.Errorf("Integer(bad) error = %v", err)
+	}
+	if tm, err := r.TimeOfDay("13:14:15"); err != nil || tm.Hour() != 13 || tm.Year() != 0 {
+		t.Errorf("TimeOfDay = %v, %v", tm, err)
+	}
+	if r.Calls("Number") != 1 || r.Calls("Integer") != 1 {
+		t.Errorf("Calls not counted")
+	}
+}
+
+func TestResolverTypedValues(t *testing.T) {
+	c := NewConfig("num=\x1bn3.14\ncount=\x1bi42\nwhen=\x1bd10/17/2026\nat=\x1bt13:14:15\nlots=\x1bnlots", "",
+		WithNumber("lots", 1e6))
+	if n, err := c.NumberE("num"); n != 3.14 || err != nil {
+		t.Errorf("NumberE(num) = %v, %v", n, err)
+	}
+	if i, err := c.IntegerE("count"); i != 42 || err != nil {
+		t.Errorf("IntegerE(count) = %v, %v", i, err)
+	}
+	if d, err := c.DateE("when"); !d.Equal(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)) || err != nil {
+		t.Errorf("DateE(when) = %v, %v", d, err)
+	}
+	if tm, err := c.TimeE("at"); tm.Hour() != 13 || tm.Second() != 15 || err != nil {
+		t.Errorf("TimeE(at) = %v, %v", tm, err)
+	}
+	if n, err := c.NumberE("lots"); n != 1e6 || err != nil {
+		t.Errorf("NumberE(lots) = %v, %v", n, err)
+	}
+	AssertNoFailure(t, c)
+}
+
+func TestResolveNeutral(t *testing.T) {
+	c := NewConfig("a=hello\nb=42", "")
+	for _, name := range []string{"a", "b"} {
+		d, err := c.Resolve(c.Value(name))
+		if d.Type != pic.Neutral || err != nil {
+			t.Errorf("Resolve(%s) = %+v, %v", name, d, err)
+		}
+	}
+}
+
+func TestFonts(t *testing.T) {
+	guid := pic.GUID{0xCA, 0xFE}
+	style := pic.GUID{0xF0, 0x0D}
+	c := NewConfig(
+		fmt.Sprintf("title=\x1bf%v|0,0,0,100|1\nlabel=\x1bf$%v\nmissing=\x1bf$%v", guid, style, pic.GUID{1}),
+		"",
+		WithFont(guid, pic.FontResource{Typeface: "Title", PointSize: 14}),
+		WithStyle(style, pic.FontStyle{FontResource: &pic.FontResource{Typeface: "Label"}, Underline: true}),
+	)
+	if fs := c.ResolveFont(c.Font("title")); fs.Typeface != "Title" || !fs.Underline {
+		t.Errorf("Title font %+v", fs)
+	}
+	if fs := c.ResolveFont(c.Font("label")); fs.Typeface != "Label" || !fs.Underline {
+		t.Errorf("Label font %+v", fs)
+	}
+	if fs := c.ResolveFont(pic.DefaultFont); fs.Typeface != "Go" || fs.TruetypeFont == nil {
+		t.Errorf("Default font %+v", fs)
+	}
+	_, err := c.ResolveFontE(c.Font("missing"))
+	AssertReturnCode(t, err, pic.UnresolvedFont)
+}
+
+type titleClient struct{}
+
+type titleBuilder struct {
+	c *pic.Config
+}
+
+func (titleClient) NewBuilder(c *pic.Config) pic.Builder {
+	return titleBuilder{c}
+}
+
+func (titleBuilder) SetFormat(format *pic.ImageFormat, colorSpace *pic.ColorSpace) {
+}
+
+func (titleBuilder) SetSize(width, height pic.Twiplet, dpi int32) {
+}
+
+func (b titleBuilder) Render() (*bytes.Buffer, error) {
+	return bytes.NewBufferString(fmt.Sprint(b.c.Integer("size"))), nil
+}
+
+func TestCreateImage(t *testing.T) {
+	data, rc := CreateImage(titleClient{}, "size=42", "")
+	AssertOK(t, rc)
+	if string(data) != "42" {
+		t.Errorf("Image %q", data)
+	}
+
+	_, rc = CreateImage(titleClient{}, "size=big", "", WithOptions(pic.Options{Strict: true}))
+	if rc != pic.InvalidDataString {
+		t.Errorf("Return code %v in strict mode", rc)
+	}
+
+	_, rc = CreateImage(titleClient{}, "size=42", "", WithImage(pic.ImageSpec{}))
+	if rc != pic.InvalidValue {
+		t.Errorf("Return code %v for zero size", rc)
+	}
+}
@@ -0,0 +1,211 @@
+package pictest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+	"github.com/PreciselyData/compose-chart-api/pic/local"
+)
+
+// Resolver is a fake of Designer/Generate. Numbers, dates and times are
+// converted by a local.Resolver: unless configured otherwise it parses
+// numbers with the default number format, dates in the form M/D/YYYY and
+// times in the form HH:MM:SS. The answers set by WithNumber and WithError
+// are given instead, and the Go Regular font is the default font. It is
+// safe for concurrent use.
+type Resolver struct {
+	local   *local.Resolver
+	opts    []local.Option
+	numbers map[string]float64
+	errors  map[string]error
+	fonts   map[pic.GUID]pic.FontResource
+	styles  map[pic.GUID]pic.FontStyle
+	image   pic.ImageSpec
+	options pic.Options
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// Option configures a Resolver.
+type Option func(r *Resolver)
+
+// NewResolver creates a Resolver with the options.
+func NewResolver(opts ...Option) *Resolver {
+	r := &Resolver{
+		numbers: make(map[string]float64),
+		errors:  make(map[string]error),
+		fonts:   map[pic.GUID]pic.FontResource{{}: GoFont()},
+		styles:  make(map[pic.GUID]pic.FontStyle),
+		image:   DefaultImage,
+		calls:   make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.local = local.NewResolver(r.opts...)
+	return r
+}
+
+// GoFont gets a font resource for the Go Regular font at 10 points.
+func GoFont() pic.FontResource {
+	return local.GoFont()
+}
+
+// WithNumberFormat sets the number format, which is also used to parse
+// integers and numbers.
+func WithNumberFormat(nf pic.NumberFormat) Option {
+	return func(r *Resolver) { r.opts = append(r.opts, local.WithNumberFormat(nf)) }
+}
+
+// WithDateTimeFormat sets the date and time format.
+func WithDateTimeFormat(dtf pic.DateTimeFormat) Option {
+	return func(r *Resolver) { r.opts = append(r.opts, local.WithDateTimeFormat(dtf)) }
+}
+
+// WithDateLayout sets the layout, in the form used by time.Parse, in which
+// dates are parsed.
+func WithDateLayout(layout string) Option {
+	return func(r *Resolver) { r.opts = append(r.opts, local.WithDateLayout(layout)) }
+}
+
+// WithTimeLayout sets the layout, in the form used by time.Parse, in which
+// times are parsed.
+func WithTimeLayout(layout string) Option {
+	return func(r *Resolver) { r.opts = append(r.opts, local.WithTimeLayout(layout)) }
+}
+
+// WithNumber sets the answer given when asked to convert s to a number or
+// an integer, instead of parsing it. A typed value is matched by its text.
+func WithNumber(s string, n float64) Option {
+	return func(r *Resolver) { r.numbers[s] = n }
+}
+
+// WithError makes converting s fail with the error.
+func WithError(s string, err error) Option {
+	return func(r *Resolver) { r.errors[s] = err }
+}
+
+// WithFont sets the font resource for the GUID. The zero GUID is that of
+// the default font.
+func WithFont(guid pic.GUID, fr pic.FontResource) Option {
+	return func(r *Resolver) { r.fonts[guid] = fr }
+}
+
+// WithStyle sets the font style for the GUID.
+func WithStyle(guid pic.GUID, fs pic.FontStyle) Option {
+	return func(r *Resolver) { r.styles[guid] = fs }
+}
+
+// WithImage sets the image required by CreateImage.
+func WithImage(spec pic.ImageSpec) Option {
+	return func(r *Resolver) { r.image = spec }
+}
+
+// WithOptions sets the options used by CreateImage.
+func WithOptions(o pic.Options) Option {
+	return func(r *Resolver) { r.options = o }
+}
+
+// Calls gets the number of times the callback, such as "FontResource", has
+// been called.
+func (r *Resolver) Calls(callback string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls[callback]
+}
+
+func (r *Resolver) called(callback string) {
+	r.mu.Lock()
+	r.calls[callback]++
+	r.mu.Unlock()
+}
+
+// Integer parses an integer, which may contain thousands separators.
+func (r *Resolver) Integer(s string) (int32, error) {
+	r.called("Integer")
+	v := pic.Value(s).Text()
+	if err, ok := r.errors[v]; ok {
+		return 0, err
+	}
+	if n, ok := r.numbers[v]; ok {
+		return int32(n), nil
+	}
+	return r.local.Integer(s)
+}
+
+// Number parses a number in the number format.
+func (r *Resolver) Number(s string) (float64, error) {
+	r.called("Number")
+	v := pic.Value(s).Text()
+	if err, ok := r.errors[v]; ok {
+		return 0, err
+	}
+	if n, ok := r.numbers[v]; ok {
+		return n, nil
+	}
+	return r.local.Number(s)
+}
+
+// Date parses a date in the date layout.
+func (r *Resolver) Date(s string) (time.Time, error) {
+	r.called("Date")
+	if err, ok := r.errors[pic.Value(s).Text()]; ok {
+		return time.Time{}, err
+	}
+	return r.local.Date(s)
+}
+
+// TimeOfDay parses a time in the time layout.
+func (r *Resolver) TimeOfDay(s string) (time.Time, error) {
+	r.called("TimeOfDay")
+	if err, ok := r.errors[pic.Value(s).Text()]; ok {
+		return time.Time{}, err
+	}
+	return r.local.TimeOfDay(s)
+}
+
+// DataValue converts a data value of the type.
+func (r *Resolver) DataValue(s string, t pic.DataType) (pic.Datum, error) {
+	r.called("DataValue")
+	return local.ConvertDataValue(r, s, t)
+}
+
+// NumberFormat gets the number format.
+func (r *Resolver) NumberFormat() pic.NumberFormat {
+	r.called("NumberFormat")
+	return r.local.NumberFormat()
+}
+
+// DateTimeFormat gets the date and time format.
+func (r *Resolver) DateTimeFormat() pic.DateTimeFormat {
+	r.called("DateTimeFormat")
+	return r.local.DateTimeFormat()
+}
+
+// FontResource gets a copy of the font resource for the GUID.
+func (r *Resolver) FontResource(guid pic.GUID) (*pic.FontResource, error) {
+	r.called("FontResource")
+	fr, ok := r.fonts[guid]
+	if !ok {
+		return nil, fmt.Errorf("font not found for guid %v", guid)
+	}
+	return &fr, nil
+}
+
+// FontStyle gets a copy of the font style for the GUID.
+func (r *Resolver) FontStyle(guid pic.GUID) (*pic.FontStyle, error) {
+	r.called("FontStyle")
+	fs, ok := r.styles[guid]
+	if !ok {
+		return nil, fmt.Errorf("style not found for guid %v", guid)
+	}
+	fr := pic.FontResource{}
+	if fs.FontResource != nil {
+		fr = *fs.FontResource
+	}
+	fs.FontResource = &fr
+	return &fs, nil
+}
@@ -0,0 +1,157 @@
+package preview
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+	"github.com/PreciselyData/compose-chart-api/pic/proptemplate"
+)
+
+// pointTwiplets is the number of Twiplets in a point. Measurements are
+// edited in points.
+const pointTwiplets = pic.Inch / 72
+
+// control is the form control of a property, with the value shown.
+type control struct {
+	proptemplate.Property
+	Field     string // Name of the form field.
+	Value     string
+	Color     string // #rrggbb of a cp or fp property.
+	Underline bool
+}
+
+// field gets the name of the form field of a property.
+func field(id string) string {
+	return "p." + id
+}
+
+// newControl creates the control of a property showing its value in the
+// configuration. Values that cannot be converted are shown as the default
+// value of the property type.
+func newControl(p proptemplate.Property, c *pic.Config) control {
+	ctl := control{Property: p, Field: field(p.ID), Value: c.Value(p.ID).Text()}
+	switch p.Type {
+	case "cp":
+		color, _ := c.ColorE(p.ID)
+		ctl.Color = formatColor(color)
+	case "fp":
+		f, err := c.FontE(p.ID)
+		if err != nil {
+			f = pic.Font{Color: pic.DefaultColor}
+		}
+		ctl.Value = formatGUID(f)
+		ctl.Color = formatColor(f.Color)
+		ctl.Underline = f.Underline
+	case "mu":
+		t, err := c.TwipletE(p.ID)
+		if err != nil {
+			t = 0
+		}
+		ctl.Value = strconv.FormatFloat(float64(t)/pointTwiplets, 'f', -1, 64)
+	}
+	return ctl
+}
+
+// setProperty sets the property from the values of its form fields, if the
+// form has them.
+func setProperty(b *pic.PropertiesBuilder, p proptemplate.Property, form url.Values) error {
+	name := field(p.ID)
+	values, ok := form[name]
+	if !ok || len(values) == 0 {
+		return nil
+	}
+	// A checkbox follows a hidden field holding the unchecked value.
+	v := values[len(values)-1]
+	switch p.Type {
+	case "cp":
+		color, err := parseColor(v)
+		if err != nil {
+			return err
+		}
+		b.SetColor(p.ID, color)
+	case "fp":
+		f, err := parseGUID(v)
+		if err != nil {
+			return err
+		}
+		if !f.IsStyle {
+			if f.Color, err = parseColor(form.Get(name + ".color")); err != nil {
+				return err
+			}
+			u := form[name+".underline"]
+			f.Underline = len(u) > 0 && u[len(u)-1] == "true"
+		}
+		b.SetFont(p.ID, f)
+	case "mu":
+		points, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsInf(points, 0) || math.IsNaN(points) || math.Abs(points) > math.MaxInt32/pointTwiplets {
+			return fmt.Errorf("invalid measurement '%s'", v)
+		}
+		b.Set(p.ID, pic.Value(strconv.Itoa(int(math.Round(points*pointTwiplets)))))
+	case "int":
+		if _, err := strconv.ParseInt(v, 10, 32); err != nil {
+			return fmt.Errorf("invalid integer '%s'", v)
+		}
+		b.Set(p.ID, pic.Value(v))
+	default:
+		b.Set(p.ID, pic.Value(v))
+	}
+	return nil
+}
+
+// formatColor formats the RGB values of a colour as #rrggbb.
+func formatColor(c pic.Color) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// parseColor parses a colour in the form #rrggbb, deriving its CMYK values
+// from its RGB values.
+func parseColor(s string) (pic.Color, error) {
+	rgb, err := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 24)
+	if err != nil || len(s) != 7 || s[0] != '#' {
+		return pic.Color{}, fmt.Errorf("invalid color '%s'", s)
+	}
+	c := pic.Color{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb)}
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	k := 1 - math.Max(r, math.Max(g, b))
+	c.K = uint8(math.Round(k * 100))
+	if k < 1 {
+		c.C = uint8(math.Round((1 - r - k) / (1 - k) * 100))
+		c.M = uint8(math.Round((1 - g - k) / (1 - k) * 100))
+		c.Y = uint8(math.Round((1 - b - k) / (1 - k) * 100))
+	}
+	return c, nil
+}
+
+// formatGUID formats the GUID of a font, prefixed by $ for a font style.
+// The default font is shown as an empty GUID.
+func formatGUID(f pic.Font) string {
+	s := ""
+	if !f.GUID.IsZero() {
+		s = f.GUID.String()
+	}
+	if f.IsStyle {
+		s = "$" + s
+	}
+	return s
+}
+
+// parseGUID parses the GUID of a font as formatted by formatGUID.
+func parseGUID(s string) (pic.Font, error) {
+	s = strings.TrimSpace(s)
+	f := pic.Font{}
+	if strings.HasPrefix(s, "$") {
+		f.IsStyle = true
+		s = s[1:]
+	}
+	if s == "" {
+		return f, nil
+	}
+	guid, err := pic.ParseGUID(s)
+	f.GUID = guid
+	return f, err
+}
@@ -0,0 +1,76 @@
+package preview
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+	"github.com/PreciselyData/compose-chart-api/pic/local"
+	"github.com/PreciselyData/compose-chart-api/pic/proptemplate"
+)
+
+// Main is the main function of a command that serves the preview of the
+// configurations of a chart engine registered with pic. Use it in the main
+// function of a program that links in the chart engine:
+//
+//	picpreview [-addr localhost:8080] [-dir folder] config/go-chart.xml
+//
+// The cfg files are read from the folder of the xml file unless -dir is
+// given.
+func Main() {
+	os.Exit(run(os.Args, os.Stderr, http.ListenAndServe))
+}
+
+func run(args []string, stderr io.Writer, listen func(addr string, h http.Handler) error) int {
+	fs := flag.NewFlagSet(filepath.Base(args[0]), flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", "localhost:8080", "address to serve the preview on")
+	dir := fs.String("dir", "", "folder of cfg files (default the folder of the xml file)")
+	var opts []local.Option
+	fs.Func("numberformat", "thousands separator and decimal point, such as ,. or .,", func(s string) error {
+		nf, err := local.ParseNumberFormat(s)
+		opts = append(opts, local.WithNumberFormat(nf))
+		return err
+	})
+	fs.Func("fonts", "folder of font files, indexed by fonts.cfg (default Go Regular for every font)", func(s string) error {
+		opts = append(opts, local.WithFontDir(s))
+		return nil
+	})
+	strict := fs.Bool("strict", false, "fail if a value cannot be converted, as with Options.Strict")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [flags] <engine>.xml\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	t, err := proptemplate.Read(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *dir == "" {
+		*dir = filepath.Dir(fs.Arg(0))
+	}
+	s := &Server{
+		Template: t,
+		Dir:      *dir,
+		Resolver: local.NewResolver(opts...),
+		Options:  pic.Options{Strict: *strict},
+	}
+	fmt.Fprintf(stderr, "Serving %s on http://%s/\n", t.Name, *addr)
+	if err := listen(*addr, s); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,301 @@
+package preview
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+	"github.com/PreciselyData/compose-chart-api/pic/pictest"
+	"github.com/PreciselyData/compose-chart-api/pic/proptemplate"
+)
+
+func TestControls(t *testing.T) {
+	c := pictest.NewConfig("color=15\nfont=\x1bfCAFE000000000000000000000000F00D|0,0,255,0|1\nstyle=\x1bf$CAFE000000000000000000000000F00D\nwidth=7200\ntitle=Sales\nbad=d10", "")
+	for _, test := range []struct {
+		p    proptemplate.Property
+		want control
+	}{
+		{proptemplate.Property{ID: "color", Type: "cp"}, control{Value: "15", Color: "#ffffff"}},
+		{proptemplate.Property{ID: "font", Type: "fp"}, control{Value: "CAFE000000000000000000000000F00D", Color: "#0000ff", Underline: true}},
+		{proptemplate.Property{ID: "style", Type: "fp"}, control{Value: "$CAFE000000000000000000000000F00D", Color: "#000000"}},
+		{proptemplate.Property{ID: "bad", Type: "fp"}, control{Value: "", Color: "#000000"}},
+		{proptemplate.Property{ID: "width", Type: "mu"}, control{Value: "3.6"}},
+		{proptemplate.Property{ID: "title", Type: "vp"}, control{Value: "Sales"}},
+	} {
+		ctl := newControl(test.p, c)
+		test.want.Property = test.p
+		test.want.Field = "p." + test.p.ID
+		if !reflect.DeepEqual(ctl, test.want) {
+			t.Errorf("newControl(%s) = %+v, want %+v", test.p.ID, ctl, test.want)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	form := url.Values{
+		"p.color":           {"#ff3333"},
+		"p.font":            {"cafe000000000000000000000000f00d"},
+		"p.font.color":      {"#000000"},
+		"p.font.underline":  {"false", "true"},
+		"p.style":           {"$CAFE000000000000000000000000F00D"},
+		"p.style.color":     {"#ffffff"},
+		"p.width":           {"3.6"},
+		"p.legend":          {"false", "true"},
+		"p.opacity":         {"50"},
+		"p.legendPos":       {"top"},
+		"p.title":           {"Sales"},
+		"p.missing.comment": {"not a property"},
+	}
+	var b pic.PropertiesBuilder
+	for _, p := range []proptemplate.Property{
+		{ID: "color", Type: "cp"},
+		{ID: "font", Type: "fp"},
+		{ID: "style", Type: "fp"},
+		{ID: "width", Type: "mu"},
+		{ID: "legend", Type: "bool"},
+		{ID: "opacity", Type: "int"},
+		{ID: "legendPos", Type: "optSort"},
+		{ID: "title", Type: "vp"},
+		{ID: "missing", Type: "vp"},
+	} {
+		if err := setProperty(&b, p, form); err != nil {
+			t.Fatalf("set(%s): %v", p.ID, err)
+		}
+	}
+	props, _, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "color=1,0,16724787,5263360\n" +
+		"font=\x1bfCAFE000000000000000000000000F00D|1,0,0,100|1\n" +
+		"style=\x1bf$CAFE000000000000000000000000F00D\n" +
+		"width=7200\nlegend=true\nopacity=50\nlegendPos=top\ntitle=Sales\n"
+	if props != want {
+		t.Errorf("Properties %q, want %q", props, want)
+	}
+
+	for _, test := range []struct {
+		p     proptemplate.Property
+		value string
+		err   string
+	}{
+		{proptemplate.Property{ID: "x", Type: "cp"}, "red", "invalid color 'red'"},
+		{proptemplate.Property{ID: "x", Type: "cp"}, "#12345", "invalid color '#12345'"},
+		{proptemplate.Property{ID: "x", Type: "fp"}, "CAFE", "invalid GUID format 'CAFE'"},
+		{proptemplate.Property{ID: "x", Type: "mu"}, "1e300", "invalid measurement '1e300'"},
+		{proptemplate.Property{ID: "x", Type: "int"}, "1.5", "invalid integer '1.5'"},
+	} {
+		err := setProperty(&b, test.p, url.Values{"p.x": {test.value}, "p.x.color": {"#000000"}})
+		if err == nil || err.Error() != test.err {
+			t.Errorf("set(%s, %q) = %v, want %s", test.p.Type, test.value, err, test.err)
+		}
+	}
+}
+
+// titleClient draws the title and size of the chart as text in SVG format,
+// or as an empty image in PNG format.
+type titleClient struct{}
+
+type titleBuilder struct {
+	*pic.Config
+	format        pic.ImageFormat
+	width, height int
+}
+
+func (titleClient) NewBuilder(c *pic.Config) pic.Builder {
+	return &titleBuilder{Config: c}
+}
+
+func (b *titleBuilder) SetFormat(format *pic.ImageFormat, colorSpace *pic.ColorSpace) {
+	if *format != pic.SVG {
+		*format = pic.PNG
+	}
+	b.format = *format
+}
+
+func (b *titleBuilder) SetSize(width, height pic.Twiplet, dpi int32) {
+	b.width, b.height = width.Pixels(dpi), height.Pixels(dpi)
+}
+
+func (b *titleBuilder) Render() (*bytes.Buffer, error) {
+	title := b.Value("title")
+	if title == "" {
+		return nil, &pic.Error{Code: pic.MissingProperty, Property: "title"}
+	}
+	buf := &bytes.Buffer{}
+	if b.format == pic.PNG {
+		err := png.Encode(buf, image.NewGray(image.Rect(0, 0, b.width, b.height)))
+		return buf, err
+	}
+	fmt.Fprintf(buf, "<svg width=\"%d\" height=\"%d\"><text>%s</text></svg>", b.width, b.height, title)
+	return buf, nil
+}
+
+func newServer(t *testing.T) *Server {
+	dir := t.TempDir()
+	pictest.WriteFiles(t, dir, map[string]string{
+		"e.xml": `<propertyTemplate id="e" name="Engine">
+  <category id="main" name="Main">
+    <property id="title" name="Title" type="vp"/>
+    <property id="legend" name="Legend" type="bool"/>
+    <property id="titleFont" name="Title Font" type="fp" indent="1" enable="legend=true"/>
+  </category>
+  <configuration id="bar" name="Bar"><categoryRef id="main"/></configuration>
+  <configuration id="pie" name="Pie"><categoryRef id="main"/></configuration>
+</propertyTemplate>`,
+		"e.cfg":     "engine=e\ntitle=Sales\nlegend=false\ndata.values=1,2,3\ndata.colors=d1\ntitleFont=d10\n",
+		"e-bar.cfg": "config=bar\n",
+		"e-pie.cfg": "config=pie\n",
+	})
+	tmpl, err := proptemplate.Read(filepath.Join(dir, "e.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Server{Template: tmpl, Dir: dir, Client: titleClient{}}
+}
+
+func get(t *testing.T, s *Server, target string) (*http.Response, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+	resp := w.Result()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestServePage(t *testing.T) {
+	s := newServer(t)
+	resp, body := get(t, s, "/")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Status %d: %s", resp.StatusCode, body)
+	}
+	for _, want := range []string{
+		`<a href="/?config=bar" class="current">Bar</a>`,
+		`<a href="/?config=pie">Pie</a>`,
+		`<input type="hidden" name="config" value="bar">`,
+		`<input type="text" id="p.title" name="p.title" value="Sales">`,
+		`<input type="checkbox" id="p.legend" name="p.legend" value="true">`,
+		`<div class="prop indent1" data-enable="legend=true">`,
+		`name="p.titleFont.color" value="#000000"`,
+		`<input type="text" id="p.data.values" name="p.data.values" value="1,2,3">`,
+		`<input type="text" id="p.data.colors" name="p.data.colors" value="d1">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Page does not contain %s", want)
+		}
+	}
+
+	if resp, _ := get(t, s, "/?config=nope"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Status %d, want 404", resp.StatusCode)
+	}
+	if resp, _ := get(t, s, "/favicon.ico"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestServeImage(t *testing.T) {
+	s := newServer(t)
+	resp, body := get(t, s, "/image?config=pie&format=svg&width=2&height=1.5&dpi=100&p.title=Profit")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Status %d: %s", resp.StatusCode, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "image/svg+xml" {
+		t.Errorf("Content-Type %s", ct)
+	}
+	if want := `<svg width="200" height="150"><text>Profit</text></svg>`; body != want {
+		t.Errorf("Image %q, want %q", body, want)
+	}
+
+	resp, body = get(t, s, "/image?config=pie&format=png")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/png" {
+		t.Fatalf("Status %d: %s", resp.StatusCode, body)
+	}
+	if _, err := png.Decode(strings.NewReader(body)); err != nil {
+		t.Error(err)
+	}
+
+	resp, body = get(t, s, "/image?config=pie&p.title=")
+	if resp.StatusCode != http.StatusUnprocessableEntity || !strings.Contains(body, "MissingProperty: property 'title'") {
+		t.Errorf("Status %d: %s", resp.StatusCode, body)
+	}
+	resp, body = get(t, s, "/image?config=pie&format=gif")
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(body, "invalid format 'gif'") {
+		t.Errorf("Status %d: %s", resp.StatusCode, body)
+	}
+	resp, body = get(t, s, "/image?config=pie&width=0")
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(body, "invalid width '0'") {
+		t.Errorf("Status %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestServeProperties(t *testing.T) {
+	s := newServer(t)
+	q := url.Values{
+		"config":             {"pie"},
+		"p.title":            {"d3"},
+		"p.legend":           {"false", "true"},
+		"p.titleFont":        {""},
+		"p.titleFont.color":  {"#ff0000"},
+		"p.data.values":      {"4,5"},
+		"p.engine":           {"other"},
+		"p.data.titles":      {"A"},
+		"p.titleFont.colour": {"x"},
+		"p.data.colors":      {"d1"},
+		"p.data.labels":      {"d2"},
+	}
+	resp, body := get(t, s, "/properties?"+q.Encode())
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Status %d: %s", resp.StatusCode, body)
+	}
+	// The tokens of the defaults are expanded, but not values typed in the
+	// form, such as the title, that look like tokens.
+	want := "engine=e\ntitle=d3\nlegend=true\ndata.values=4,5\ndata.colors=\x011,0,15896107,2707973\n" +
+		"titleFont=\x1bf00000000000000000000000000000000|1,0,16711680,6579200|0\nconfig=pie\n" +
+		"data.labels=d2\ndata.titles=A\ntitleFont.colour=x\n"
+	if body != want {
+		t.Errorf("Properties %q, want %q", body, want)
+	}
+
+	resp, body = get(t, s, "/properties?config=pie&p.title=a%0Ab")
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(body, "property 'title'") {
+		t.Errorf("Status %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestRun(t *testing.T) {
+	s := newServer(t)
+	var stderr bytes.Buffer
+	var served *Server
+	rc := run([]string{"picpreview", "-addr", ":0", "-numberformat", ".,", filepath.Join(s.Dir, "e.xml")}, &stderr,
+		func(addr string, h http.Handler) error {
+			served = h.(*Server)
+			return nil
+		})
+	if rc != 0 {
+		t.Fatalf("Exit code %d: %s", rc, stderr.String())
+	}
+	if served.Dir != s.Dir || served.Template.ID != "e" || served.Resolver.NumberFormat().DecimalPoint != ',' {
+		t.Errorf("Server %+v", served)
+	}
+	if !strings.Contains(stderr.String(), "Serving Engine on http://:0/") {
+		t.Errorf("Output %q", stderr.String())
+	}
+
+	stderr.Reset()
+	if rc := run([]string{"picpreview", filepath.Join(s.Dir, "missing.xml")}, &stderr, nil); rc != 1 {
+		t.Errorf("Exit code %d, want 1", rc)
+	}
+	if rc := run([]string{"picpreview"}, &stderr, nil); rc != 2 {
+		t.Errorf("Exit code %d, want 2", rc)
+	}
+}
@@ -0,0 +1,292 @@
+// Package preview serves a web page that reproduces the Plug-in Chart
+// dialog of Designer, so that property templates and chart engines can be
+// developed and demonstrated without Designer. The page lists the
+// configurations of the engine's property template, shows a form control
+// for each property of a configuration, and creates the chart again with
+// the engine's Client on every edit, showing the PNG and SVG images side by
+// side together with the properties passed to the engine.
+//
+//	s := &preview.Server{Template: t, Dir: "config", Client: &client{}}
+//	http.ListenAndServe("localhost:8080", s)
+package preview
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+	"github.com/PreciselyData/compose-chart-api/pic/cfg"
+	"github.com/PreciselyData/compose-chart-api/pic/local"
+	"github.com/PreciselyData/compose-chart-api/pic/proptemplate"
+)
+
+//go:embed page.html
+var files embed.FS
+
+var page = template.Must(template.ParseFS(files, "page.html"))
+
+// Server is an http.Handler serving the preview of the configurations of a
+// chart engine.
+type Server struct {
+	// Template is the property template of the engine.
+	Template *proptemplate.Template
+	// Dir is the folder of cfg files holding the default values of the
+	// configurations; see package cfg.
+	Dir string
+	// Client creates the images. If nil, the Client registered with pic
+	// for the engine is used.
+	Client pic.Client
+	// Resolver answers the questions asked of Designer/Generate. If nil,
+	// a local.Resolver with the default options is used.
+	Resolver pic.Resolver
+	// Options are passed to pic.CreateImage.
+	Options pic.Options
+	// Defaults expands the default colour and font tokens of the cfg
+	// files, as Designer does. It is pic.NewDefaultsTable() if nil.
+	Defaults *pic.DefaultsTable
+}
+
+// ServeHTTP serves the page of a configuration (/?config=<id>, by default
+// the first), its images (/image?config=<id>&format=png|svg&...) and its
+// properties (/properties?config=<id>&...). The images and properties
+// reflect the values of the page's form fields in the query.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch r.URL.Path {
+	case "/":
+		s.servePage(w, r)
+	case "/image":
+		s.serveImage(w, r)
+	case "/properties":
+		s.serveProperties(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type pageData struct {
+	Template      *proptemplate.Template
+	Configuration *proptemplate.Configuration
+	Categories    []categoryData
+	Spec          pic.ImageSpec
+}
+
+type categoryData struct {
+	Name     string
+	Controls []control
+}
+
+func (s *Server) servePage(w http.ResponseWriter, r *http.Request) {
+	c, err := s.configuration(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defaults, err := cfg.Load(s.Dir, s.Template.ID, c.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	props, err := s.expand(defaults)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	config := pic.NewConfig(s.resolver(), props, "")
+
+	data := pageData{Template: s.Template, Configuration: c, Spec: defaultSpec}
+	shown := map[string]bool{"engine": true, "config": true}
+	for _, cat := range c.Categories {
+		cd := categoryData{Name: cat.Name}
+		for _, p := range cat.Properties {
+			cd.Controls = append(cd.Controls, newControl(p, config))
+			shown[p.ID] = true
+		}
+		data.Categories = append(data.Categories, cd)
+	}
+	// The defaults of properties not in the template, such as the data,
+	// are edited as text, with any tokens unexpanded; see properties.
+	other := categoryData{Name: "Other"}
+	for _, setting := range defaults {
+		if !shown[setting.Name] {
+			p := proptemplate.Property{ID: setting.Name, Name: setting.Name}
+			ctl := newControl(p, config)
+			ctl.Value = setting.Value
+			other.Controls = append(other.Controls, ctl)
+			shown[setting.Name] = true
+		}
+	}
+	if len(other.Controls) > 0 {
+		data.Categories = append(data.Categories, other)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := page.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// defaultSpec is the image shown until the size is changed on the page.
+var defaultSpec = pic.ImageSpec{Width: 4 * pic.Inch, Height: 3 * pic.Inch, DPI: 96, Format: pic.PNG, ColorSpace: pic.RGB}
+
+var contentTypes = map[pic.ImageFormat]string{
+	pic.BMP: "image/bmp",
+	pic.PNG: "image/png",
+	pic.JPG: "image/jpeg",
+	pic.SVG: "image/svg+xml",
+}
+
+func (s *Server) serveImage(w http.ResponseWriter, r *http.Request) {
+	props, err := s.properties(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	spec, err := imageSpec(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := pic.CreateImage(s.Client, s.resolver(), props, "", &spec, s.Options)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypes[spec.Format])
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(data)
+}
+
+func (s *Server) serveProperties(w http.ResponseWriter, r *http.Request) {
+	props, err := s.properties(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, props)
+}
+
+// expand expands the default colour and font tokens in the cfg file
+// settings of a configuration, as Designer does when it loads them.
+func (s *Server) expand(defaults cfg.Settings) (string, error) {
+	d := s.Defaults
+	if d == nil {
+		d = pic.NewDefaultsTable()
+	}
+	return d.Expand(defaults.String(), s.Template.ColorAndFontProperties()...)
+}
+
+func (s *Server) resolver() pic.Resolver {
+	if s.Resolver == nil {
+		return local.NewResolver()
+	}
+	return s.Resolver
+}
+
+// configuration gets the configuration requested, by default the first.
+func (s *Server) configuration(r *http.Request) (*proptemplate.Configuration, error) {
+	id := r.Form.Get("config")
+	if id == "" && len(s.Template.Configurations) > 0 {
+		return &s.Template.Configurations[0], nil
+	}
+	if c, ok := s.Template.Configuration(id); ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("configuration '%s' not found", id)
+}
+
+// properties gets the properties of the configuration requested: its
+// defaults, with the default colour and font tokens expanded, overridden by
+// the values of the form fields in the request. The values of the form are
+// passed as they are, so that a title such as d1 is not taken for a token.
+func (s *Server) properties(r *http.Request) (string, error) {
+	c, err := s.configuration(r)
+	if err != nil {
+		return "", err
+	}
+	defaults, err := cfg.Load(s.Dir, s.Template.ID, c.ID)
+	if err != nil {
+		return "", err
+	}
+	expanded, err := s.expand(defaults)
+	if err != nil {
+		return "", err
+	}
+
+	var b pic.PropertiesBuilder
+	fields := map[string]bool{field("engine"): true, field("config"): true}
+	for _, setting := range cfg.Parse(expanded) {
+		b.Set(setting.Name, pic.Value(setting.Value))
+	}
+	for _, cat := range c.Categories {
+		for _, p := range cat.Properties {
+			if err := setProperty(&b, p, r.Form); err != nil {
+				return "", fmt.Errorf("property '%s': %w", p.ID, err)
+			}
+			name := field(p.ID)
+			fields[name], fields[name+".color"], fields[name+".underline"] = true, true, true
+		}
+	}
+	// The other fields are of the properties edited as text. Those left
+	// as their defaults keep the expanded defaults.
+	var other []string
+	for name := range r.Form {
+		id, ok := strings.CutPrefix(name, "p.")
+		if !ok || fields[name] {
+			continue
+		}
+		if v, ok := defaults.Get(id); ok && v == r.Form.Get(name) {
+			continue
+		}
+		other = append(other, id)
+	}
+	sort.Strings(other)
+	for _, id := range other {
+		p := proptemplate.Property{ID: id}
+		if err := setProperty(&b, p, r.Form); err != nil {
+			return "", fmt.Errorf("property '%s': %w", id, err)
+		}
+	}
+	props, _, err := b.Build()
+	return props, err
+}
+
+func imageSpec(r *http.Request) (pic.ImageSpec, error) {
+	spec := defaultSpec
+	for _, dim := range []struct {
+		name string
+		t    *pic.Twiplet
+	}{{"width", &spec.Width}, {"height", &spec.Height}} {
+		if s := r.Form.Get(dim.name); s != "" {
+			in, err := strconv.ParseFloat(s, 64)
+			if err != nil || in <= 0 || in > 100 {
+				return spec, fmt.Errorf("invalid %s '%s'", dim.name, s)
+			}
+			*dim.t = pic.Twiplet(in*pic.Inch + 0.5)
+		}
+	}
+	if s := r.Form.Get("dpi"); s != "" {
+		dpi, err := strconv.Atoi(s)
+		if err != nil || dpi <= 0 || dpi > 1200 {
+			return spec, fmt.Errorf("invalid dpi '%s'", s)
+		}
+		spec.DPI = int32(dpi)
+	}
+	switch f := r.Form.Get("format"); f {
+	case "", "png":
+	case "svg":
+		spec.Format = pic.SVG
+	default:
+		return spec, fmt.Errorf("invalid format '%s'", f)
+	}
+	return spec, nil
+}
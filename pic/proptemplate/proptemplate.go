@@ -0,0 +1,269 @@
+// Package proptemplate reads the property template of a chart engine, the
+// xml file that describes the configurations and properties shown by
+// Designer in the Plug-in Chart dialog. It is shared by the preview, render
+// and golden packages.
+package proptemplate
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Template is the property template of a chart engine, read from the xml
+// file listed by Designer in the Plug-in Chart dialog.
+type Template struct {
+	ID             string
+	Name           string
+	Configurations []Configuration
+}
+
+// Configuration is a configuration of the engine, with the categories of
+// properties shown for it in the dialog.
+type Configuration struct {
+	ID, Name   string
+	Categories []Category
+}
+
+// Category is a group of properties shown together in the dialog.
+type Category struct {
+	ID, Name   string
+	Properties []Property
+}
+
+// Property is a property shown in the dialog. Type is one of vp, fp, cp,
+// mu, bool, int, opt and optSort; see the README for their meaning.
+type Property struct {
+	ID, Name, Type string
+	Description    string
+	Indent         int
+	Enable         string // Condition, such as legend=true, enabling it.
+	Min, Max       string // Limits of an int property, if any.
+	Options        []Option
+}
+
+// Option is an option of an opt or optSort property.
+type Option struct {
+	ID, Name string
+}
+
+// element is an element of the xml file, whose children are kept in order.
+type element struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []element  `xml:",any"`
+}
+
+func (e *element) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Read reads the property template xml file of an engine.
+func Read(name string) (*Template, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	t, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return t, nil
+}
+
+// Parse parses a property template. References to categories,
+// data sets and property groups that are not defined are ignored.
+func Parse(b []byte) (*Template, error) {
+	var root element
+	if err := xml.Unmarshal(b, &root); err != nil {
+		return nil, err
+	}
+	if root.XMLName.Local != "propertyTemplate" {
+		return nil, fmt.Errorf("root element is %s, not propertyTemplate", root.XMLName.Local)
+	}
+
+	p := &parser{
+		categories: make(map[string]*element),
+		groups:     make(map[string]*element),
+	}
+	for i := range root.Children {
+		e := &root.Children[i]
+		switch e.XMLName.Local {
+		case "category", "dataSet":
+			p.categories[e.attr("id")] = e
+		case "propertyGroup":
+			p.groups[e.attr("id")] = e
+		}
+	}
+
+	t := &Template{ID: root.attr("id"), Name: root.attr("name")}
+	for i := range root.Children {
+		e := &root.Children[i]
+		if e.XMLName.Local != "configuration" {
+			continue
+		}
+		c := Configuration{ID: e.attr("id"), Name: e.attr("name")}
+		for j := range e.Children {
+			child := &e.Children[j]
+			switch child.XMLName.Local {
+			case "categoryRef", "dataSetRef":
+				child = p.categories[child.attr("id")]
+			case "category":
+			default:
+				continue
+			}
+			if child == nil {
+				continue
+			}
+			cat, err := p.category(child)
+			if err != nil {
+				return nil, fmt.Errorf("configuration %s: %w", c.ID, err)
+			}
+			if len(cat.Properties) > 0 {
+				c.Categories = append(c.Categories, cat)
+			}
+		}
+		t.Configurations = append(t.Configurations, c)
+	}
+	return t, nil
+}
+
+type parser struct {
+	categories map[string]*element
+	groups     map[string]*element
+}
+
+func (p *parser) category(e *element) (Category, error) {
+	c := Category{ID: e.attr("id"), Name: e.attr("name")}
+	props, err := p.properties(e, "", nil, 0)
+	c.Properties = props
+	return c, err
+}
+
+// properties gets the properties of a category or property group, with
+// the ids of those in a referenced group prefixed. The depth guards against
+// groups that refer to themselves.
+func (p *parser) properties(e *element, prefix string, remove map[string]bool, depth int) ([]Property, error) {
+	if depth > 10 {
+		return nil, fmt.Errorf("property group %s nested too deeply", e.attr("id"))
+	}
+	var props []Property
+	for i := range e.Children {
+		child := &e.Children[i]
+		switch child.XMLName.Local {
+		case "property":
+			// Data styles are edited with the data, not as a property.
+			if child.attr("type") == "dataStyle" || remove[child.attr("id")] {
+				continue
+			}
+			prop, err := p.property(child, prefix)
+			if err != nil {
+				return nil, err
+			}
+			props = append(props, prop)
+		case "propertyGroupRef":
+			group := p.groups[child.attr("id")]
+			if group == nil {
+				continue
+			}
+			removed := make(map[string]bool)
+			for _, id := range strings.Split(child.attr("remove"), ",") {
+				removed[strings.TrimSpace(id)] = true
+			}
+			groupProps, err := p.properties(group, prefix+child.attr("prefix"), removed, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			props = append(props, groupProps...)
+		}
+	}
+	return props, nil
+}
+
+func (p *parser) property(e *element, prefix string) (Property, error) {
+	prop := Property{
+		ID:          prefix + e.attr("id"),
+		Name:        e.attr("name"),
+		Type:        e.attr("type"),
+		Description: e.attr("description"),
+		Enable:      e.attr("enable"),
+		Min:         e.attr("min"),
+		Max:         e.attr("max"),
+	}
+	if prop.Enable != "" && prefix != "" {
+		prop.Enable = prefix + prop.Enable
+	}
+	if s := e.attr("indent"); s != "" {
+		indent, err := strconv.Atoi(s)
+		if err != nil {
+			return Property{}, fmt.Errorf("property %s: invalid indent '%s'", prop.ID, s)
+		}
+		prop.Indent = indent
+	}
+	for _, child := range e.Children {
+		if child.XMLName.Local == "option" {
+			prop.Options = append(prop.Options, Option{ID: child.attr("id"), Name: child.attr("name")})
+		}
+	}
+	if prop.Type == "optSort" {
+		sort.SliceStable(prop.Options, func(i, j int) bool {
+			return strings.ToLower(prop.Options[i].Name) < strings.ToLower(prop.Options[j].Name)
+		})
+	}
+	return prop, nil
+}
+
+// Configuration gets the configuration with the id.
+func (t *Template) Configuration(id string) (*Configuration, bool) {
+	for i := range t.Configurations {
+		if t.Configurations[i].ID == id {
+			return &t.Configurations[i], true
+		}
+	}
+	return nil, false
+}
+
+// ColorAndFontProperties gets the ids of the colour and font properties, of
+// type cp and fp, of every configuration. Designer expands the default
+// colour and font tokens of these properties; see pic.DefaultsTable.Expand.
+func (t *Template) ColorAndFontProperties() []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, c := range t.Configurations {
+		for _, cat := range c.Categories {
+			for _, p := range cat.Properties {
+				if (p.Type == "cp" || p.Type == "fp") && !seen[p.ID] {
+					ids = append(ids, p.ID)
+					seen[p.ID] = true
+				}
+			}
+		}
+	}
+	return ids
+}
+
+// ReadColorAndFontProperties reads the colour and font properties of an
+// engine from its xml file, <engine>.xml in the folder of its cfg files. No
+// properties are returned if there is no xml file, in which case only the
+// tokens of data.colors and data.fonts are expanded.
+func ReadColorAndFontProperties(dir, engine string) ([]string, error) {
+	t, err := Read(filepath.Join(dir, engine+".xml"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t.ColorAndFontProperties(), nil
+}
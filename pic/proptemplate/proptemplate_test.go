@@ -0,0 +1,95 @@
+package proptemplate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	tmpl, err := Read("../../example/go-chart/config/go-chart.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.ID != "go-chart" || tmpl.Name != "Go-chart example" {
+		t.Errorf("Template %s %q", tmpl.ID, tmpl.Name)
+	}
+	var ids []string
+	for _, c := range tmpl.Configurations {
+		ids = append(ids, c.ID)
+	}
+	if want := []string{"pie", "donut", "line"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Configurations %v, want %v", ids, want)
+	}
+
+	line, ok := tmpl.Configuration("line")
+	if !ok {
+		t.Fatal("line configuration not found")
+	}
+	var cats []string
+	for _, cat := range line.Categories {
+		cats = append(cats, cat.Name)
+	}
+	// The general category is not defined, and the data set only has a
+	// data style.
+	if want := []string{"Presentation", "Legend", "Axis"}; !reflect.DeepEqual(cats, want) {
+		t.Errorf("Categories %v, want %v", cats, want)
+	}
+	pos := line.Categories[1].Properties[1]
+	want := Property{
+		ID: "legendPos", Name: "Position", Type: "optSort", Indent: 1, Enable: "legend=true",
+		Options: []Option{{"left", "Left"}, {"top", "Top"}},
+	}
+	if !reflect.DeepEqual(pos, want) {
+		t.Errorf("Property %+v, want %+v", pos, want)
+	}
+
+	props, err := ReadColorAndFontProperties("../../example/go-chart/config", "go-chart")
+	if want := []string{"titleFont", "bgColor", "legendColor", "axisFont"}; err != nil || !reflect.DeepEqual(props, want) {
+		t.Errorf("ReadColorAndFontProperties() = %v, %v, want %v", props, err, want)
+	}
+	if props, err := ReadColorAndFontProperties("../../example/go-chart/config", "none"); props != nil || err != nil {
+		t.Errorf("ReadColorAndFontProperties(none) = %v, %v", props, err)
+	}
+}
+
+func TestParseGroups(t *testing.T) {
+	tmpl, err := Parse([]byte(`<propertyTemplate id="e" name="E">
+  <propertyGroup id="axis">
+    <property id="Show" name="Show" type="bool"/>
+    <property id="Font" name="Font" type="fp" enable="Show=true"/>
+    <property id="Color" name="Color" type="cp"/>
+  </propertyGroup>
+  <configuration id="bar" name="Bar">
+    <category id="axes" name="Axes">
+      <propertyGroupRef id="axis" prefix="x"/>
+      <propertyGroupRef id="axis" prefix="y" remove="Color, Font"/>
+      <property id="sort" name="Sort" type="optSort">
+        <option id="z" name="zebra"/>
+        <option id="a" name="Apple"/>
+        <option id="m" name="mango"/>
+      </property>
+    </category>
+  </configuration>
+</propertyTemplate>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := tmpl.Configurations[0].Categories[0].Properties
+	var ids []string
+	for _, p := range props {
+		ids = append(ids, p.ID)
+	}
+	if want := []string{"xShow", "xFont", "xColor", "yShow", "sort"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Properties %v, want %v", ids, want)
+	}
+	if props[1].Enable != "xShow=true" {
+		t.Errorf("Enable %q", props[1].Enable)
+	}
+	if want := []Option{{"a", "Apple"}, {"m", "mango"}, {"z", "zebra"}}; !reflect.DeepEqual(props[4].Options, want) {
+		t.Errorf("Options %v, want %v", props[4].Options, want)
+	}
+
+	if _, err := Parse([]byte(`<chart/>`)); err == nil {
+		t.Error("Parse() of chart element succeeded")
+	}
+}
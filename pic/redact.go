@@ -0,0 +1,151 @@
+package pic
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Redaction specifies how customer data is kept out of the log, capture
+// files and crash reports. The zero Redaction redacts nothing.
+//
+// Once anything is redacted, the data values passed to the callbacks of
+// Designer/Generate and their answers are also redacted, as are the
+// messages of errors, which are reduced to the ReturnCode and the name of
+// the property. A redacted capture can still be replayed, but the image
+// is created from the redacted values.
+type Redaction struct {
+	// Symbols redacts the values of all symbols.
+	Symbols bool
+
+	// AllowProperties, if not nil, redacts the values of all properties
+	// other than those named.
+	AllowProperties []string
+
+	// Hash replaces each redacted value with a hash of the value instead
+	// of masking it, so that equal values can still be recognised. The
+	// hash is keyed with a random key chosen when the process starts, so
+	// that low-entropy values such as amounts and dates cannot be found by
+	// hashing guesses; equal values only hash alike within one process.
+	Hash bool
+}
+
+// redactedMask replaces redacted values unless they are hashed.
+const redactedMask = "***"
+
+// hashKey gets the key of the HMAC of hashed values, generated once per
+// process.
+var hashKey = sync.OnceValue(func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("pic: cannot generate redaction key: %v", err))
+	}
+	return key
+})
+
+// redactor applies a Redaction. A nil redactor redacts nothing.
+type redactor struct {
+	hash       bool
+	allSymbols bool
+	allow      map[string]bool // Nil if all properties are allowed.
+}
+
+func (rd Redaction) redactor() *redactor {
+	if !rd.Symbols && rd.AllowProperties == nil {
+		return nil
+	}
+	r := &redactor{hash: rd.Hash, allSymbols: rd.Symbols}
+	if rd.AllowProperties != nil {
+		r.allow = make(map[string]bool, len(rd.AllowProperties))
+		for _, name := range rd.AllowProperties {
+			r.allow[name] = true
+		}
+	}
+	return r
+}
+
+// value redacts a value. Empty values are kept.
+func (r *redactor) value(v string) string {
+	if r == nil || v == "" {
+		return v
+	}
+	if r.hash {
+		mac := hmac.New(sha256.New, hashKey())
+		mac.Write([]byte(v))
+		return "#" + hex.EncodeToString(mac.Sum(nil)[:6])
+	}
+	return redactedMask
+}
+
+// properties redacts the values of the properties that are not allowed.
+// References to symbols are kept, as they are only names.
+func (r *redactor) properties(props string) string {
+	if r == nil || r.allow == nil {
+		return props
+	}
+	return r.settings(props, func(name, value string) bool {
+		return !r.allow[name] && (value == "" || value[0] != ascDLE)
+	})
+}
+
+// symbols redacts the values of all symbols.
+func (r *redactor) symbols(syms string) string {
+	if r == nil || !r.allSymbols {
+		return syms
+	}
+	return r.settings(syms, func(name, value string) bool { return true })
+}
+
+// settings redacts the values of the name=value lines for which redact
+// returns true, keeping the lines in order.
+func (r *redactor) settings(input string, redact func(name, value string) bool) string {
+	lines := strings.Split(input, "\n")
+	for i, line := range lines {
+		cr := strings.HasSuffix(line, "\r")
+		setting := strings.SplitN(strings.TrimSuffix(line, "\r"), "=", 2)
+		if len(setting) == 2 && redact(setting[0], setting[1]) {
+			lines[i] = setting[0] + "=" + r.value(setting[1])
+			if cr {
+				lines[i] += "\r"
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// datum redacts a data value given by Designer/Generate.
+func (r *redactor) datum(d Datum) Datum {
+	if r == nil {
+		return d
+	}
+	return Datum{Type: d.Type}
+}
+
+// text formats a value for the log, redacting it.
+func (r *redactor) text(v interface{}) interface{} {
+	if r == nil {
+		return v
+	}
+	return r.value(fmt.Sprint(v))
+}
+
+// error gets the message of an error, reduced to the ReturnCode and the
+// property name when redacting.
+func (r *redactor) error(err error) string {
+	if r == nil {
+		return err.Error()
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		return redactedMask
+	}
+	if e.Property != "" {
+		return fmt.Sprintf("%v: property '%s'", e.Code, e.Property)
+	}
+	return e.Code.String()
+}
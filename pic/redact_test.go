@@ -0,0 +1,96 @@
+package pic
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactProperties(t *testing.T) {
+	r := Redaction{AllowProperties: []string{"config", "engine"}}.redactor()
+	props := "config=pie\r\nengine=test\r\ntitle=Balance\r\nvalue=\x10sym\r\nempty="
+	assertEqual(t, r.properties(props), "config=pie\r\nengine=test\r\ntitle=***\r\nvalue=\x10sym\r\nempty=")
+	assertEqual(t, r.symbols("sym=42"), "sym=42")
+}
+
+func TestRedactSymbols(t *testing.T) {
+	r := Redaction{Symbols: true}.redactor()
+	assertEqual(t, r.symbols("name=Smith\nbalance=\x1bn42.5"), "name=***\nbalance=***")
+	assertEqual(t, r.properties("title=Balance"), "title=Balance")
+}
+
+func TestRedactHash(t *testing.T) {
+	r := Redaction{Symbols: true, Hash: true}.redactor()
+	a, b := r.value("Smith"), r.value("Jones")
+	assertEqual(t, strings.HasPrefix(a, "#"), true)
+	assertEqual(t, len(a), 13)
+	assertEqual(t, a != b, true)
+	assertEqual(t, r.value("Smith"), a)
+
+	// The hash is keyed, so a guess cannot be confirmed by hashing it.
+	sum := sha256.Sum256([]byte("Smith"))
+	assertEqual(t, a != "#"+hex.EncodeToString(sum[:6]), true)
+}
+
+func TestRedactNothing(t *testing.T) {
+	var r *redactor = Redaction{Hash: true}.redactor()
+	assertEqual(t, r == nil, true)
+	assertEqual(t, r.properties("title=Balance"), "title=Balance")
+	assertEqual(t, r.value("Smith"), "Smith")
+	assertEqual(t, r.error(errors.New("Smith")), "Smith")
+}
+
+func TestRedactError(t *testing.T) {
+	r := Redaction{Symbols: true}.redactor()
+	err := &Error{Code: InvalidDataString, Property: "size", Err: errors.New("invalid integer 'Smith'")}
+	assertEqual(t, r.error(err), "InvalidDataString: property 'size'")
+	assertEqual(t, r.error(errors.New("invalid integer 'Smith'")), redactedMask)
+}
+
+func TestRedactCall(t *testing.T) {
+	var logBuf bytes.Buffer
+	h := slog.NewJSONHandler(&logBuf, &slog.HandlerOptions{Level: LevelTrace})
+	defer SetOptions(Options{})
+	SetOptions(Options{LogLevel: LogTrace, LogHandler: h})
+
+	dir := t.TempDir()
+	o := load().options
+	o.Strict = true
+	o.CaptureDir = dir
+	o.CrashDir = dir
+	o.CrashReportConfig = true
+	o.Redact = Redaction{Symbols: true, AllowProperties: []string{"config"}}
+	defer withClient(renderClient(func(c *Config) (*bytes.Buffer, error) {
+		c.Resolve(c.Value("name"))
+		c.Number("balance")
+		c.Integer("size")
+		panic("oops")
+	}), o)()
+
+	props := "config=pie\nname=\x10customer\nbalance=1234.5\nsize=Smith"
+	assertEqual(t, createImage(newMockCallback(), props, "customer=\x1bnJones", testImage()), Failed)
+
+	var files bytes.Buffer
+	names, _ := filepath.Glob(filepath.Join(dir, "*"))
+	assertEqual(t, len(names), 2)
+	for _, name := range names {
+		b, _ := os.ReadFile(name)
+		files.Write(b)
+	}
+	for _, secret := range []string{"Jones", "1234.5", "Smith"} {
+		if strings.Contains(logBuf.String(), secret) {
+			t.Errorf("Log contains %q:\n%s", secret, logBuf.String())
+		}
+		if strings.Contains(files.String(), secret) {
+			t.Errorf("Capture or crash report contains %q:\n%s", secret, files.String())
+		}
+	}
+	assertContains(t, logBuf.String(), `"callback":"dataValue"`, `"in":"***"`)
+	assertContains(t, files.String(), "config=pie", `"Redacted": true`)
+}
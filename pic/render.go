@@ -0,0 +1,119 @@
+package pic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// render creates the chart image via the builder. If the timeout elapses
+// before the image is created, the builder is abandoned and the resolver
+// is closed so that the builder can no longer call Designer/Generate.
+func render(c *Config, b Builder, o Options) (*cBuffer, error) {
+	if o.RenderTimeout <= 0 {
+		return buildImage(context.Background(), c, b, o.Strict)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.RenderTimeout)
+	defer cancel()
+
+	r := &guardedResolver{Resolver: c.resolver}
+	c.resolver = r
+
+	type result struct {
+		buf *cBuffer
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var res result
+		defer func() {
+			if r := recover(); r != nil {
+				res.err = recovered(r)
+			}
+			done <- res
+		}()
+		res.buf, res.err = buildImage(ctx, c, b, o.Strict)
+	}()
+
+	select {
+	case res := <-done:
+		return res.buf, res.err
+	case <-ctx.Done():
+		r.close()
+		go func() {
+			// Free the image if the abandoned builder ever completes.
+			if res := <-done; res.buf != nil {
+				res.buf.free()
+			}
+		}()
+		err := fmt.Errorf("render timed out after %v", o.RenderTimeout)
+		return nil, &Error{Code: Failed, Err: err}
+	}
+}
+
+// buildImage creates the chart image in memory allocated by C, which is
+// freed unless the image is created successfully.
+func buildImage(ctx context.Context, c *Config, b Builder, strict bool) (*cBuffer, error) {
+	buf := &cBuffer{}
+	ok := false
+	defer func() {
+		if !ok {
+			buf.free()
+		}
+	}()
+	if err := build(ctx, c, b, strict, buf); err != nil {
+		return nil, err
+	}
+	ok = true
+	return buf, nil
+}
+
+func build(ctx context.Context, c *Config, b Builder, strict bool, w io.Writer) error {
+	var err error
+	switch b := b.(type) {
+	case StreamBuilder:
+		err = b.RenderTo(&contextWriter{ctx: ctx, w: w})
+	case ContextBuilder:
+		err = writeImage(w)(b.RenderContext(ctx))
+	default:
+		err = writeImage(w)(b.Render())
+	}
+	if strict {
+		if ferr := c.Err(); ferr != nil {
+			return ferr
+		}
+	}
+	return err
+}
+
+// writeImage returns a function that writes the image created by Render
+// or RenderContext.
+func writeImage(w io.Writer) func(buf *bytes.Buffer, err error) error {
+	return func(buf *bytes.Buffer, err error) error {
+		if err != nil {
+			return err
+		}
+		if buf == nil {
+			return errors.New("no image data rendered")
+		}
+		_, err = buf.WriteTo(w)
+		return err
+	}
+}
+
+// contextWriter fails once its context is done, so that a StreamBuilder
+// stops rendering when the render timeout elapses.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw *contextWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
@@ -0,0 +1,104 @@
+package render
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+	"github.com/PreciselyData/compose-chart-api/pic/cfg"
+	"github.com/PreciselyData/compose-chart-api/pic/local"
+)
+
+// Main is the main function of a command that creates the chart images of
+// configurations with the clients registered with pic. Use it in the main
+// function of a program that links in the chart engine:
+//
+//	picrender [flags] config/go-chart-line.cfg...
+//
+// Each cfg file is merged over the cfg file of its engine, go-chart.cfg in
+// the example, and the image written to the output folder as
+// <engine>-<id>.<format>, the format being the one the engine created.
+func Main() {
+	os.Exit(run(os.Args, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(filepath.Base(args[0]), flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	out := fs.String("o", ".", "folder to write the images to")
+	spec := pic.ImageSpec{Width: 4 * pic.Inch, Height: 3 * pic.Inch, DPI: 96, Format: pic.PNG, ColorSpace: pic.RGB}
+	fs.Func("width", "image width in inches, such as 4in, or in Twiplets (default 4in)", func(s string) (err error) {
+		spec.Width, err = ParseLength(s)
+		return err
+	})
+	fs.Func("height", "image height in inches, such as 3in, or in Twiplets (default 3in)", func(s string) (err error) {
+		spec.Height, err = ParseLength(s)
+		return err
+	})
+	dpi := fs.Int("dpi", 96, "image resolution in dots per inch")
+	fs.Func("format", "image format: bmp, png, jpg or svg (default png)", func(s string) (err error) {
+		spec.Format, err = ParseImageFormat(s)
+		return err
+	})
+	fs.Func("colorspace", "image colour space: named, rgb or cmyk (default rgb)", func(s string) (err error) {
+		spec.ColorSpace, err = ParseColorSpace(s)
+		return err
+	})
+	var opts []local.Option
+	fs.Func("numberformat", "thousands separator and decimal point, such as ,. or .,", func(s string) error {
+		nf, err := local.ParseNumberFormat(s)
+		opts = append(opts, local.WithNumberFormat(nf))
+		return err
+	})
+	fs.Func("fonts", "folder of font files, indexed by fonts.cfg (default Go Regular for every font)", func(s string) error {
+		opts = append(opts, local.WithFontDir(s))
+		return nil
+	})
+	strict := fs.Bool("strict", false, "fail if a value cannot be converted, as with Options.Strict")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [flags] <engine>-<id>.cfg...\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || *dpi <= 0 {
+		fs.Usage()
+		return 2
+	}
+	spec.DPI = int32(*dpi)
+
+	r := local.NewResolver(opts...)
+	o := pic.Options{Strict: *strict}
+	failed := false
+	for _, name := range fs.Args() {
+		file, err := renderFile(r, name, *out, spec, o)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", name, err)
+			failed = true
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: wrote %s\n", name, file)
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func renderFile(r pic.Resolver, name, out string, spec pic.ImageSpec, o pic.Options) (string, error) {
+	c, err := cfg.Find(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := Render(nil, r, c, &spec, o)
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(out, c.Name()+"."+strings.ToLower(spec.Format.String()))
+	return file, os.WriteFile(file, data, 0o644)
+}
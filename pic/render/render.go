@@ -0,0 +1,78 @@
+// Package render creates the chart image of a configuration from its cfg
+// files, merged as Designer merges them, with a local resolver standing in
+// for Designer/Generate. Main runs it as the picrender command, which lets
+// chart engines be developed without Designer on Windows.
+package render
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+	"github.com/PreciselyData/compose-chart-api/pic/cfg"
+	"github.com/PreciselyData/compose-chart-api/pic/proptemplate"
+)
+
+// Render creates the chart image of a configuration with the client and the
+// resolver, expanding the default colour and font tokens of the cfg files
+// with pic.NewDefaultsTable. The colour and font properties are read from
+// the engine's xml file in the folder of the cfg files, if there is one. If
+// client is nil, the Client registered with pic for the engine of the
+// configuration is used. The spec is updated with the format and colour
+// space of the image created.
+func Render(client pic.Client, r pic.Resolver, c cfg.Configuration, spec *pic.ImageSpec, o pic.Options) ([]byte, error) {
+	settings, err := c.Load()
+	if err != nil {
+		return nil, err
+	}
+	names, err := proptemplate.ReadColorAndFontProperties(c.Dir, c.Engine)
+	if err != nil {
+		return nil, err
+	}
+	props, err := pic.NewDefaultsTable().Expand(settings.String(), names...)
+	if err != nil {
+		return nil, err
+	}
+	return pic.CreateImage(client, r, props, "", spec, o)
+}
+
+// ParseLength parses a length in inches, such as 4in or 2.5in, or in
+// Twiplets, such as 576000.
+func ParseLength(s string) (pic.Twiplet, error) {
+	if in, ok := strings.CutSuffix(s, "in"); ok {
+		f, err := strconv.ParseFloat(in, 64)
+		if err != nil || f <= 0 {
+			return 0, fmt.Errorf("invalid length '%s'", s)
+		}
+		return pic.Twiplet(f*pic.Inch + 0.5), nil
+	}
+	i, err := strconv.ParseInt(s, 10, 32)
+	if err != nil || i <= 0 {
+		return 0, fmt.Errorf("invalid length '%s'", s)
+	}
+	return pic.Twiplet(i), nil
+}
+
+// ParseImageFormat parses the name of an image format, such as png or SVG.
+func ParseImageFormat(s string) (pic.ImageFormat, error) {
+	for _, f := range []pic.ImageFormat{pic.BMP, pic.PNG, pic.JPG, pic.SVG} {
+		if strings.EqualFold(s, f.String()) {
+			return f, nil
+		}
+	}
+	if strings.EqualFold(s, "jpeg") {
+		return pic.JPG, nil
+	}
+	return 0, fmt.Errorf("invalid image format '%s'", s)
+}
+
+// ParseColorSpace parses the name of a colour space, such as rgb or CMYK.
+func ParseColorSpace(s string) (pic.ColorSpace, error) {
+	for _, cs := range []pic.ColorSpace{pic.Named, pic.RGB, pic.CMYK} {
+		if strings.EqualFold(s, cs.String()) {
+			return cs, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid color space '%s'", s)
+}
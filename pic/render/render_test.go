@@ -0,0 +1,147 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+	"github.com/PreciselyData/compose-chart-api/pic/pictest"
+)
+
+// textClient writes the title, size, number and colour of the configuration
+// as text, in SVG format.
+type textClient struct{}
+
+type textBuilder struct {
+	*pic.Config
+	width, height int
+}
+
+func (textClient) NewBuilder(c *pic.Config) pic.Builder {
+	return &textBuilder{Config: c}
+}
+
+func (*textBuilder) SetFormat(format *pic.ImageFormat, colorSpace *pic.ColorSpace) {
+	*format = pic.SVG
+}
+
+func (b *textBuilder) SetSize(width, height pic.Twiplet, dpi int32) {
+	b.width, b.height = width.Pixels(dpi), height.Pixels(dpi)
+}
+
+func (b *textBuilder) Render() (*bytes.Buffer, error) {
+	if b.Value("title") == "" {
+		return nil, &pic.Error{Code: pic.MissingProperty, Property: "title"}
+	}
+	b.Font("font")
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s %s %dx%d %g %v", b.Name(), b.Value("title"), b.width, b.height, b.Number("total"), b.Color("color").R)
+	return buf, nil
+}
+
+func TestParseLength(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		want pic.Twiplet
+	}{
+		{"4in", 4 * pic.Inch},
+		{"2.5in", 2.5 * pic.Inch},
+		{"576000", 576000},
+	} {
+		if l, err := ParseLength(test.s); err != nil || l != test.want {
+			t.Errorf("ParseLength(%q) = %v, %v, want %v", test.s, l, err, test.want)
+		}
+	}
+	for _, s := range []string{"", "in", "4cm", "4.5", "0", "-1in"} {
+		if _, err := ParseLength(s); err == nil {
+			t.Errorf("ParseLength(%q) succeeded", s)
+		}
+	}
+}
+
+func TestParseImageFormat(t *testing.T) {
+	for s, want := range map[string]pic.ImageFormat{"png": pic.PNG, "SVG": pic.SVG, "jpeg": pic.JPG, "bmp": pic.BMP} {
+		if f, err := ParseImageFormat(s); err != nil || f != want {
+			t.Errorf("ParseImageFormat(%q) = %v, %v", s, f, err)
+		}
+	}
+	if _, err := ParseImageFormat("gif"); err == nil {
+		t.Error("ParseImageFormat(gif) succeeded")
+	}
+	if cs, err := ParseColorSpace("cmyk"); err != nil || cs != pic.CMYK {
+		t.Errorf("ParseColorSpace(cmyk) = %v, %v", cs, err)
+	}
+	if _, err := ParseColorSpace("grey"); err == nil {
+		t.Error("ParseColorSpace(grey) succeeded")
+	}
+}
+
+func TestRun(t *testing.T) {
+	pic.Register("text", textClient{})
+	t.Cleanup(func() { pic.Register("text", nil) })
+	dir, out := t.TempDir(), t.TempDir()
+	pictest.WriteFiles(t, dir, map[string]string{
+		"text.xml": `<propertyTemplate id="text" name="Text">
+  <category id="main" name="Main">
+    <property id="color" name="Color" type="cp"/>
+    <property id="font" name="Font" type="fp"/>
+  </category>
+  <configuration id="sum" name="Sum"><categoryRef id="main"/></configuration>
+</propertyTemplate>`,
+		"text.cfg":        "engine=text\ntitle=Sales\ntotal=0\ncolor=d0\nfont=d10\n",
+		"text-sum.cfg":    "config=sum\ntotal=1.234,5\n",
+		"text-empty.cfg":  "config=empty\ntitle=\n",
+		"orphan-none.cfg": "engine=orphan\n",
+	})
+
+	var stdout, stderr bytes.Buffer
+	rc := run([]string{
+		"picrender", "-o", out, "-width", "2in", "-height", "72000", "-dpi", "100",
+		"-format", "png", "-numberformat", ".,", "-strict",
+		filepath.Join(dir, "text-sum.cfg"),
+	}, &stdout, &stderr)
+	if rc != 0 {
+		t.Fatalf("Exit code %d: %s", rc, stderr.String())
+	}
+	file := filepath.Join(out, "text-sum.svg")
+	if !strings.Contains(stdout.String(), "wrote "+file) {
+		t.Errorf("Output %q", stdout.String())
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); s != "sum Sales 200x50 1234.5 78" {
+		t.Errorf("Image %q", s)
+	}
+
+	stdout.Reset()
+	rc = run([]string{
+		"picrender", "-o", out,
+		filepath.Join(dir, "text-empty.cfg"),
+		filepath.Join(dir, "orphan-none.cfg"),
+	}, &stdout, &stderr)
+	if rc != 1 {
+		t.Errorf("Exit code %d, want 1", rc)
+	}
+	for _, msg := range []string{
+		"text-empty.cfg: MissingProperty: property 'title'",
+		"orphan-none.cfg: no engine cfg file found",
+	} {
+		if !strings.Contains(stderr.String(), msg) {
+			t.Errorf("Error output %q does not contain %q", stderr.String(), msg)
+		}
+	}
+
+	stderr.Reset()
+	if rc := run([]string{"picrender", "-format", "gif", "x.cfg"}, &stdout, &stderr); rc != 2 {
+		t.Errorf("Exit code %d, want 2", rc)
+	}
+	if !strings.Contains(stderr.String(), "invalid image format 'gif'") {
+		t.Errorf("Error output %q", stderr.String())
+	}
+}
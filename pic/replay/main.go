@@ -0,0 +1,65 @@
+package replay
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Main is the main function of a command that replays capture files with
+// the clients registered with pic, reporting whether each image is the same
+// as the captured one. Use it in the main function of a program that links
+// in the chart engine:
+//
+//	picreplay [-o dir] capture.json...
+//
+// With -o, the images are written to the folder, named after the captures.
+func Main() {
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ExitOnError)
+	out := fs.String("o", "", "folder to write the images to")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [-o dir] capture.json...\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	fs.Parse(os.Args[1:])
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, name := range fs.Args() {
+		err := replayFile(name, *out)
+		if err == ErrRedacted {
+			fmt.Printf("%s: %v\n", name, err)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: OK\n", name)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func replayFile(name, out string) error {
+	c, err := Load(name)
+	if err != nil {
+		return err
+	}
+	data, err := Run(nil, c)
+	if out != "" && err == nil {
+		base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+		ext := "." + strings.ToLower(c.CreatedFormat.String())
+		if werr := os.WriteFile(filepath.Join(out, base+ext), data, 0644); werr != nil {
+			return werr
+		}
+	}
+	return Check(c, data, err)
+}
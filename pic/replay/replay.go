@@ -0,0 +1,222 @@
+// Package replay creates chart images again from the captures written by
+// pic when Options.CaptureDir is set, without Designer/Generate. The
+// answers recorded in the capture stand in for Designer/Generate, and the
+// captured font files are used in place of the host's fonts, so the image
+// created is the same as the one created by the captured call.
+package replay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+)
+
+// Load reads a capture file.
+func Load(name string) (*pic.Capture, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := pic.ReadCapture(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return c, nil
+}
+
+// Run creates the image of the capture using the client. If client is nil,
+// the Client registered with pic for the engine of the configuration is
+// used.
+func Run(client pic.Client, c *pic.Capture) ([]byte, error) {
+	r, err := NewResolver(c)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	spec := c.Image
+	o := pic.Options{Strict: c.Strict, RenderTimeout: c.RenderTimeout}
+	return pic.CreateImage(client, r, c.Properties, c.Symbols, &spec, o)
+}
+
+// ErrRedacted is returned by Check for a capture from which customer data
+// was redacted, as the outcome cannot be compared with that of the captured
+// call.
+var ErrRedacted = errors.New("capture redacted, outcome not compared")
+
+// Check compares the outcome of Run with that of the captured call.
+func Check(c *pic.Capture, data []byte, err error) error {
+	if c.Redacted {
+		return ErrRedacted
+	}
+	if rc := pic.ErrorCode(err); rc != c.ReturnCode {
+		return fmt.Errorf("return code %v, captured %v", rc, c.ReturnCode)
+	}
+	if err != nil {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	if hash := hex.EncodeToString(sum[:]); hash != c.ImageHash {
+		return fmt.Errorf(
+			"image differs from capture: size %d, captured %d",
+			len(data), c.ImageSize,
+		)
+	}
+	return nil
+}
+
+// Resolver answers the questions asked of Designer/Generate with the
+// answers recorded in a capture. Questions that were not asked by the
+// captured call fail.
+type Resolver struct {
+	c     *pic.Capture
+	dir   string            // Folder holding the captured font files.
+	files map[string]string // Captured font file names by original name.
+}
+
+// NewResolver creates the resolver for the capture, writing the captured
+// font files to a temporary folder which is removed by Close.
+func NewResolver(c *pic.Capture) (*Resolver, error) {
+	r := &Resolver{c: c, files: make(map[string]string)}
+	if len(c.FontFiles) == 0 {
+		return r, nil
+	}
+	dir, err := os.MkdirTemp("", "picreplay")
+	if err != nil {
+		return nil, err
+	}
+	r.dir = dir
+	i := 0
+	for name, data := range c.FontFiles {
+		i++
+		file := filepath.Join(dir, fmt.Sprintf("%d-%s", i, baseName(name)))
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.files[name] = file
+	}
+	return r, nil
+}
+
+// baseName gets the last element of a file name from Linux or Windows.
+func baseName(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// Close removes the captured font files.
+func (r *Resolver) Close() error {
+	if r.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(r.dir)
+}
+
+func notCaptured(question, s string) error {
+	return fmt.Errorf("no captured answer for %s '%s'", question, s)
+}
+
+func (r *Resolver) value(m map[string]pic.CapturedValue, question, s string) (pic.Datum, error) {
+	v, ok := m[s]
+	if !ok {
+		return pic.Datum{Type: pic.NotSet}, notCaptured(question, s)
+	}
+	return v.Datum, v.Err()
+}
+
+// Integer answers with the captured integer.
+func (r *Resolver) Integer(s string) (int32, error) {
+	d, err := r.value(r.c.Integers, "integer", s)
+	return d.Integer, err
+}
+
+// Number answers with the captured number.
+func (r *Resolver) Number(s string) (float64, error) {
+	d, err := r.value(r.c.Numbers, "number", s)
+	return d.Number, err
+}
+
+// Date answers with the captured date.
+func (r *Resolver) Date(s string) (time.Time, error) {
+	d, err := r.value(r.c.Dates, "date", s)
+	return d.Time, err
+}
+
+// TimeOfDay answers with the captured time.
+func (r *Resolver) TimeOfDay(s string) (time.Time, error) {
+	d, err := r.value(r.c.Times, "time", s)
+	return d.Time, err
+}
+
+// DataValue answers with the captured data value.
+func (r *Resolver) DataValue(s string, t pic.DataType) (pic.Datum, error) {
+	return r.value(r.c.DataValues[t], t.String()+" data value", s)
+}
+
+// NumberFormat answers with the captured number format. The zero format is
+// returned if the captured call did not ask for it.
+func (r *Resolver) NumberFormat() pic.NumberFormat {
+	if r.c.NumberFormat == nil {
+		return pic.NumberFormat{}
+	}
+	return *r.c.NumberFormat
+}
+
+// DateTimeFormat answers with the captured date and time format. The zero
+// format is returned if the captured call did not ask for it.
+func (r *Resolver) DateTimeFormat() pic.DateTimeFormat {
+	if r.c.DateTimeFormat == nil {
+		return pic.DateTimeFormat{}
+	}
+	return *r.c.DateTimeFormat
+}
+
+// FontResource answers with the captured font resource, using the captured
+// font file.
+func (r *Resolver) FontResource(guid pic.GUID) (*pic.FontResource, error) {
+	cf, ok := r.c.FontResources[guid.String()]
+	if !ok {
+		return nil, notCaptured("font resource", guid.String())
+	}
+	if err := cf.Err(); err != nil {
+		return nil, err
+	}
+	fr := cf.FontResource()
+	fr.Filename = r.fontFile(fr.Filename)
+	return fr, nil
+}
+
+// FontStyle answers with the captured font style, using the captured font
+// file.
+func (r *Resolver) FontStyle(guid pic.GUID) (*pic.FontStyle, error) {
+	cf, ok := r.c.FontStyles[guid.String()]
+	if !ok {
+		return nil, notCaptured("font style", guid.String())
+	}
+	if err := cf.Err(); err != nil {
+		return nil, err
+	}
+	fs := cf.FontStyle()
+	fs.Filename = r.fontFile(fs.Filename)
+	return fs, nil
+}
+
+// fontFile gets the name of the captured copy of a font file. The original
+// name is kept if the file was not captured, so that the font fails to load
+// as it did in the captured call.
+func (r *Resolver) fontFile(name string) string {
+	if file, ok := r.files[name]; ok {
+		return file
+	}
+	return name
+}
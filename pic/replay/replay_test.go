@@ -0,0 +1,200 @@
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// hostResolver stands in for Designer/Generate.
+type hostResolver struct {
+	fontFile string
+}
+
+func (hostResolver) Integer(s string) (int32, error) {
+	i, err := strconv.ParseInt(s, 10, 32)
+	return int32(i), err
+}
+
+func (hostResolver) Number(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+func (hostResolver) Date(s string) (time.Time, error) {
+	return time.Parse("2/1/2006", s)
+}
+
+func (hostResolver) TimeOfDay(s string) (time.Time, error) {
+	return time.Parse("15:04:05", s)
+}
+
+func (hostResolver) DataValue(s string, t pic.DataType) (pic.Datum, error) {
+	return pic.Datum{Type: pic.NotSet}, errors.New("not supported")
+}
+
+func (hostResolver) NumberFormat() pic.NumberFormat {
+	return pic.NumberFormat{ThousandsSeparator: '.', DecimalPoint: ','}
+}
+
+func (hostResolver) DateTimeFormat() pic.DateTimeFormat {
+	return pic.DateTimeFormat{ShortDateFormat: "d/M/yyyy"}
+}
+
+func (r hostResolver) FontResource(guid pic.GUID) (*pic.FontResource, error) {
+	return &pic.FontResource{Typeface: "Go", PointSize: 10, Filename: r.fontFile}, nil
+}
+
+func (r hostResolver) FontStyle(guid pic.GUID) (*pic.FontStyle, error) {
+	return nil, fmt.Errorf("style not found for guid %v", guid)
+}
+
+type chartClient struct {
+	suffix string
+}
+
+type chartBuilder struct {
+	c      *pic.Config
+	suffix string
+}
+
+func (cc chartClient) NewBuilder(c *pic.Config) pic.Builder {
+	return &chartBuilder{c: c, suffix: cc.suffix}
+}
+
+func (*chartBuilder) SetFormat(format *pic.ImageFormat, colorSpace *pic.ColorSpace) {
+	*format = pic.SVG
+}
+
+func (*chartBuilder) SetSize(width, height pic.Twiplet, dpi int32) {
+}
+
+func (b *chartBuilder) Render() (*bytes.Buffer, error) {
+	c := b.c
+	fs := c.ResolveFont(c.Font("font"))
+	if fs.TruetypeFont == nil {
+		return nil, errors.New("font not loaded")
+	}
+	return bytes.NewBufferString(fmt.Sprintf(
+		"<svg>%d %v %v %c %s %d%s</svg>",
+		c.Integer("size"), c.Number("ratio"), c.Date("date").Format("2006-01-02"),
+		c.NumberFormat().DecimalPoint, fs.Typeface, fs.TruetypeFont.Bounds(64).Max.X,
+		b.suffix,
+	)), nil
+}
+
+func capture(t *testing.T, props string, o pic.Options) (*pic.Capture, []byte, error) {
+	dir := t.TempDir()
+	fontFile := filepath.Join(dir, "goregular.ttf")
+	if err := os.WriteFile(fontFile, goregular.TTF, 0644); err != nil {
+		t.Fatal(err)
+	}
+	o.CaptureDir = dir
+	spec := pic.ImageSpec{Width: 100000, Height: 50000, DPI: 96, Format: pic.PNG, ColorSpace: pic.RGB}
+	data, err := pic.CreateImage(chartClient{}, hostResolver{fontFile}, props, "", &spec, o)
+
+	// The captured call must not depend on the original font file.
+	os.Remove(fontFile)
+	names, _ := filepath.Glob(filepath.Join(dir, "capture-*.json"))
+	if len(names) != 1 {
+		t.Fatalf("Expected one capture file, found %d", len(names))
+	}
+	c, lerr := Load(names[0])
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+	return c, data, err
+}
+
+const chartProps = "size=42\nratio=0.5\ndate=17/10/2026\n" +
+	"font=\x1bfCAFE000000000000000000000000F00D|0,0,0,100|0"
+
+func TestRun(t *testing.T) {
+	c, want, err := capture(t, chartProps, pic.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := Run(chartClient{}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("Replayed image %q, want %q", data, want)
+	}
+	if err := Check(c, data, err); err != nil {
+		t.Error(err)
+	}
+	if c.CreatedFormat != pic.SVG {
+		t.Errorf("Created format %v, want SVG", c.CreatedFormat)
+	}
+}
+
+func TestRunDifferentClient(t *testing.T) {
+	c, _, err := capture(t, chartProps, pic.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := Run(chartClient{suffix: "!"}, c)
+	if Check(c, data, err) == nil {
+		t.Error("Different image not detected")
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	c, _, err := capture(t, chartProps+"\nsize=big", pic.Options{Strict: true})
+	if pic.ErrorCode(err) != pic.InvalidDataString {
+		t.Fatalf("Captured call returned %v", err)
+	}
+	data, err := Run(chartClient{}, c)
+	if err := Check(c, data, err); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunRedacted(t *testing.T) {
+	o := pic.Options{Redact: pic.Redaction{AllowProperties: []string{"font"}}}
+	c, _, err := capture(t, chartProps, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Redacted {
+		t.Fatal("Capture not marked as redacted")
+	}
+	data, err := Run(chartClient{}, c)
+	if err := Check(c, data, err); err != ErrRedacted {
+		t.Errorf("Check returned %v", err)
+	}
+}
+
+func TestNotCaptured(t *testing.T) {
+	r, err := NewResolver(&pic.Capture{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.Integer("1"); err == nil {
+		t.Error("Integer not captured but answered")
+	}
+	if _, err := r.FontResource(pic.GUID{}); err == nil {
+		t.Error("Font not captured but answered")
+	}
+}
+
+func TestBaseName(t *testing.T) {
+	for name, want := range map[string]string{
+		`C:\Windows\Fonts\arial.ttf`: "arial.ttf",
+		"/usr/share/fonts/arial.ttf": "arial.ttf",
+		"arial.ttf":                  "arial.ttf",
+	} {
+		if got := baseName(name); got != want {
+			t.Errorf("baseName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
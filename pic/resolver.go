@@ -1,9 +1,261 @@
 package pic
 
-type resolver interface {
-	integer(s string) (int32, error)
-	number(s string) (float64, error)
-	numberFormat() NumberFormat
-	fontResource(guid GUID) (*FontResource, error)
-	fontStyle(guid GUID) (*FontStyle, error)
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Resolver answers the questions asked of Designer/Generate while a chart
+// is created: it converts data values from the host's locale, and supplies
+// the number and date formats and the fonts. EnchCreateImage uses a Resolver
+// that calls back into Designer/Generate.
+type Resolver interface {
+	Integer(s string) (int32, error)
+	Number(s string) (float64, error)
+	Date(s string) (time.Time, error)
+	TimeOfDay(s string) (time.Time, error)
+	DataValue(s string, t DataType) (Datum, error)
+	NumberFormat() NumberFormat
+	DateTimeFormat() DateTimeFormat
+	FontResource(guid GUID) (*FontResource, error)
+	FontStyle(guid GUID) (*FontStyle, error)
+}
+
+var errResolverClosed = errors.New("resolver closed after render timed out")
+
+// guardedResolver prevents an abandoned builder from calling back into
+// Designer/Generate once EnchCreateImage has returned. Closing waits for
+// any call in progress to complete.
+type guardedResolver struct {
+	Resolver
+	mu     sync.RWMutex
+	closed bool
+}
+
+func (r *guardedResolver) close() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+}
+
+func (r *guardedResolver) Integer(s string) (int32, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return 0, errResolverClosed
+	}
+	return r.Resolver.Integer(s)
+}
+
+func (r *guardedResolver) Number(s string) (float64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return 0, errResolverClosed
+	}
+	return r.Resolver.Number(s)
+}
+
+func (r *guardedResolver) Date(s string) (time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return time.Time{}, errResolverClosed
+	}
+	return r.Resolver.Date(s)
+}
+
+func (r *guardedResolver) TimeOfDay(s string) (time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return time.Time{}, errResolverClosed
+	}
+	return r.Resolver.TimeOfDay(s)
+}
+
+func (r *guardedResolver) DataValue(s string, t DataType) (Datum, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return Datum{Type: NotSet}, errResolverClosed
+	}
+	return r.Resolver.DataValue(s, t)
+}
+
+func (r *guardedResolver) NumberFormat() NumberFormat {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return DefaultNumberFormat()
+	}
+	return r.Resolver.NumberFormat()
+}
+
+func (r *guardedResolver) DateTimeFormat() DateTimeFormat {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return DefaultDateTimeFormat()
+	}
+	return r.Resolver.DateTimeFormat()
+}
+
+func (r *guardedResolver) FontResource(guid GUID) (*FontResource, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return nil, errResolverClosed
+	}
+	return r.Resolver.FontResource(guid)
+}
+
+func (r *guardedResolver) FontStyle(guid GUID) (*FontStyle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return nil, errResolverClosed
+	}
+	return r.Resolver.FontStyle(guid)
+}
+
+// tracingResolver logs every call back into Designer/Generate at the
+// trace level.
+type tracingResolver struct {
+	Resolver
+	log    *slog.Logger
+	redact *redactor
+}
+
+func (r *tracingResolver) trace(callback string, in, out interface{}, err error) {
+	attrs := []slog.Attr{
+		slog.String("callback", callback),
+		slog.Any("in", in),
+		slog.Any("out", out),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", r.redact.error(err)))
+	}
+	r.log.LogAttrs(context.Background(), LevelTrace, "Callback", attrs...)
+}
+
+func (r *tracingResolver) Integer(s string) (int32, error) {
+	i, err := r.Resolver.Integer(s)
+	r.trace("integer", r.redact.text(s), r.redact.text(i), err)
+	return i, err
+}
+
+func (r *tracingResolver) Number(s string) (float64, error) {
+	n, err := r.Resolver.Number(s)
+	r.trace("number", r.redact.text(s), r.redact.text(n), err)
+	return n, err
+}
+
+func (r *tracingResolver) Date(s string) (time.Time, error) {
+	d, err := r.Resolver.Date(s)
+	r.trace("date", r.redact.text(s), r.redact.text(d), err)
+	return d, err
+}
+
+func (r *tracingResolver) TimeOfDay(s string) (time.Time, error) {
+	t, err := r.Resolver.TimeOfDay(s)
+	r.trace("timeOfDay", r.redact.text(s), r.redact.text(t), err)
+	return t, err
+}
+
+func (r *tracingResolver) DataValue(s string, t DataType) (Datum, error) {
+	d, err := r.Resolver.DataValue(s, t)
+	r.trace("dataValue", r.redact.text(s), r.redact.datum(d), err)
+	return d, err
+}
+
+func (r *tracingResolver) NumberFormat() NumberFormat {
+	nf := r.Resolver.NumberFormat()
+	r.trace("numberFormat", nil, nf, nil)
+	return nf
+}
+
+func (r *tracingResolver) DateTimeFormat() DateTimeFormat {
+	dtf := r.Resolver.DateTimeFormat()
+	r.trace("dateTimeFormat", nil, dtf, nil)
+	return dtf
+}
+
+func (r *tracingResolver) FontResource(guid GUID) (*FontResource, error) {
+	fr, err := r.Resolver.FontResource(guid)
+	r.trace("fontResource", guid.String(), fr, err)
+	return fr, err
+}
+
+func (r *tracingResolver) FontStyle(guid GUID) (*FontStyle, error) {
+	fs, err := r.Resolver.FontStyle(guid)
+	r.trace("fontStyle", guid.String(), fs, err)
+	return fs, err
+}
+
+// safeResolver recovers from a panic in a callback, which then fails, so
+// that the panic cannot take down Designer/Generate even if the Builder
+// made the callback from a goroutine of its own.
+type safeResolver struct {
+	Resolver
+	crashed func(function string, pe *panicError)
+}
+
+// recover must be deferred by each callback. It calls fail with the error
+// to be returned if the callback panicked.
+func (r *safeResolver) recover(callback string, fail func(err error)) {
+	if v := recover(); v != nil {
+		pe := newPanicError(v)
+		r.crashed("callback "+callback, pe)
+		fail(fmt.Errorf("callback %s: %v", callback, pe))
+	}
+}
+
+func (r *safeResolver) Integer(s string) (i int32, err error) {
+	defer r.recover("Integer", func(e error) { i, err = 0, e })
+	return r.Resolver.Integer(s)
+}
+
+func (r *safeResolver) Number(s string) (n float64, err error) {
+	defer r.recover("Number", func(e error) { n, err = 0, e })
+	return r.Resolver.Number(s)
+}
+
+func (r *safeResolver) Date(s string) (d time.Time, err error) {
+	defer r.recover("Date", func(e error) { d, err = time.Time{}, e })
+	return r.Resolver.Date(s)
+}
+
+func (r *safeResolver) TimeOfDay(s string) (t time.Time, err error) {
+	defer r.recover("TimeOfDay", func(e error) { t, err = time.Time{}, e })
+	return r.Resolver.TimeOfDay(s)
+}
+
+func (r *safeResolver) DataValue(s string, t DataType) (d Datum, err error) {
+	defer r.recover("DataValue", func(e error) { d, err = Datum{Type: NotSet}, e })
+	return r.Resolver.DataValue(s, t)
+}
+
+func (r *safeResolver) NumberFormat() (nf NumberFormat) {
+	defer r.recover("NumberFormat", func(error) { nf = DefaultNumberFormat() })
+	return r.Resolver.NumberFormat()
+}
+
+func (r *safeResolver) DateTimeFormat() (dtf DateTimeFormat) {
+	defer r.recover("DateTimeFormat", func(error) { dtf = DefaultDateTimeFormat() })
+	return r.Resolver.DateTimeFormat()
+}
+
+func (r *safeResolver) FontResource(guid GUID) (fr *FontResource, err error) {
+	defer r.recover("FontResource", func(e error) { fr, err = nil, e })
+	return r.Resolver.FontResource(guid)
+}
+
+func (r *safeResolver) FontStyle(guid GUID) (fs *FontStyle, err error) {
+	defer r.recover("FontStyle", func(e error) { fs, err = nil, e })
+	return r.Resolver.FontStyle(guid)
 }
@@ -23,6 +23,23 @@ func (rc ReturnCode) String() string {
 	return fmt.Sprintf("Unknown (%d)", rc)
 }
 
+var dataTypes = map[DataType]string{
+	NotSet:   "NotSet",
+	Neutral:  "Neutral",
+	Integer:  "Integer",
+	Number:   "Number",
+	Date:     "Date",
+	Time:     "Time",
+	Currency: "Currency",
+}
+
+func (dt DataType) String() string {
+	if s, ok := dataTypes[dt]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown (%d)", dt)
+}
+
 var imageFormats = map[ImageFormat]string{
 	BMP: "BMP",
 	PNG: "PNG",
@@ -11,6 +11,12 @@ func TestReturnCodeString(t *testing.T) {
 	assertEqual(t, fmt.Sprintf("%v", ReturnCode(999)), "Unknown (999)")
 }
 
+func TestDataTypeString(t *testing.T) {
+	assertEqual(t, fmt.Sprintf("%v", NotSet), "NotSet")
+	assertEqual(t, fmt.Sprintf("%v", Currency), "Currency")
+	assertEqual(t, fmt.Sprintf("%v", DataType(999)), "Unknown (999)")
+}
+
 func TestImageFormatString(t *testing.T) {
 	assertEqual(t, fmt.Sprintf("%v", BMP), "BMP")
 	assertEqual(t, fmt.Sprintf("%v", SVG), "SVG")
@@ -0,0 +1,95 @@
+// Command engine is a chart engine built with -buildmode=c-shared for the
+// ABI test host in the parent directory. Its image is a text description of
+// everything it resolved through Designer/Generate's callbacks, so that the
+// host can check the values that crossed the C interface in both directions.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/PreciselyData/compose-chart-api/pic"
+)
+
+type client struct{}
+
+func (client) NewBuilder(c *pic.Config) pic.Builder {
+	return &builder{Config: c}
+}
+
+type builder struct {
+	*pic.Config
+	format        pic.ImageFormat
+	colorSpace    pic.ColorSpace
+	width, height pic.Twiplet
+	dpi           int32
+}
+
+// SetFormat only creates SVG images in the RGB colour space, so that the
+// host can check that the changes are returned in EnchImage.
+func (b *builder) SetFormat(format *pic.ImageFormat, colorSpace *pic.ColorSpace) {
+	b.format, b.colorSpace = *format, *colorSpace
+	*format, *colorSpace = pic.SVG, pic.RGB
+}
+
+func (b *builder) SetSize(width, height pic.Twiplet, dpi int32) {
+	b.width, b.height, b.dpi = width, height, dpi
+}
+
+func (b *builder) Render() (*bytes.Buffer, error) {
+	title := b.Value("title")
+	if title == "" {
+		return nil, &pic.Error{
+			Code:     pic.MissingProperty,
+			Property: "title",
+			Err:      errors.New("no title"),
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "config=%s\n", b.Name())
+	fmt.Fprintf(buf, "title=%s\n", title.Text())
+	fmt.Fprintf(buf, "image=%v %v %dx%d %d\n", b.format, b.colorSpace, b.width, b.height, b.dpi)
+	fmt.Fprintf(buf, "count=%d\n", b.Integer("count"))
+	fmt.Fprintf(buf, "total=%g\n", b.Number("total"))
+	fmt.Fprintf(buf, "date=%s\n", b.Date("date").Format("2006-01-02"))
+	fmt.Fprintf(buf, "time=%s\n", b.Time("time").Format("15:04:05"))
+	if d, err := b.Resolve(b.Value("price")); err != nil {
+		fmt.Fprintf(buf, "price=%v\n", pic.ErrorCode(err))
+	} else {
+		fmt.Fprintf(buf, "price=%v %g\n", d.Type, d.Number)
+	}
+	if _, err := b.IntegerE("unknown"); err != nil {
+		fmt.Fprintf(buf, "unknown=%v\n", pic.ErrorCode(err))
+	}
+
+	nf := b.NumberFormat()
+	fmt.Fprintf(buf, "numberFormat=%q %q\n", nf.ThousandsSeparator, nf.DecimalPoint)
+	dtf := b.DateTimeFormat()
+	fmt.Fprintf(buf, "months=%d %s %s\n", len(dtf.MonthNames), dtf.MonthNames[0], dtf.MonthNames[11])
+	fmt.Fprintf(buf, "weekDays=%d %s\n", len(dtf.WeekDayNames), dtf.WeekDayNames[0])
+	fmt.Fprintf(buf, "ampm=%s %s\n", dtf.AM, dtf.PM)
+	fmt.Fprintf(buf, "formats=%s|%s|%s\n", dtf.ShortDateFormat, dtf.LongDateFormat, dtf.TimeFormat)
+
+	for _, name := range []string{"defaultFont", "font", "style"} {
+		fs, err := b.ResolveFontE(b.Font(name))
+		if err != nil {
+			fmt.Fprintf(buf, "%s=%v\n", name, pic.ErrorCode(err))
+			continue
+		}
+		fmt.Fprintf(
+			buf, "%s=%s %g %d %s %d,%d,%d %t\n",
+			name, fs.Typeface, fs.PointSize, fs.Attributes, fs.Filename,
+			fs.Color.R, fs.Color.G, fs.Color.B, fs.Underline,
+		)
+	}
+	return buf, nil
+}
+
+func init() {
+	pic.Register("abi", client{})
+	pic.SetOptions(pic.Options{})
+}
+
+func main() {}
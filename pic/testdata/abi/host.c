@@ -0,0 +1,362 @@
+//============================================================================
+// File:        host.c
+// Synopsis:    Test host that loads a chart engine built with
+//              -buildmode=c-shared and calls it as Designer/Generate does.
+//
+// Usage:       host <engine.so> <font-dir>
+//
+// The engine is testdata/abi/engine, whose image is a text description of
+// the values it resolved through the callbacks below. The callbacks answer
+// from a script of real wchar_t strings, including characters outside the
+// Basic Multilingual Plane, so that the conversions in c_interface.h are
+// checked in both directions. The font directory must contain the Go Regular
+// font named "Gö Regular 😀.ttf".
+//============================================================================
+#define _POSIX_C_SOURCE 200809L
+#define ENCH_IMPORT
+
+#include <dlfcn.h>
+#include <locale.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <wchar.h>
+#include "enchapi.h"
+
+static int cFailures = 0;
+
+#define CHECK(cond, ...) \
+   do \
+   { \
+      if (!(cond)) \
+      { \
+         fprintf(stderr, "%s:%d: ", __FILE__, __LINE__); \
+         fprintf(stderr, __VA_ARGS__); \
+         fputc('\n', stderr); \
+         cFailures++; \
+      } \
+   } while (0)
+
+//============================================================================
+// Scripted callbacks.
+//============================================================================
+
+struct tagENCHSHELLDATA
+{
+   const char* pszName;
+};
+
+static struct tagENCHSHELLDATA shellData = { "abi" };
+static EnchCallback callback;
+static wchar_t szFontFileName[ENCH_FileNameSize];
+static int cDateTimeFormats = 0;
+static int cFreedDateTimeFormats = 0;
+
+typedef struct tagScriptedValue
+{
+   const wchar_t* pszValue;
+   int nExpectedType;
+   ENCHRC rc;
+   EnchDataValue dataValue;
+} ScriptedValue;
+
+static const ScriptedValue scriptedValues[] =
+{
+   { L"42", ENCH_DataInteger, ENCHRC_OK, { ENCH_DataInteger, { .iValue = 42 } } },
+   { L"1\u00a0" L"234,5", ENCH_DataNumber, ENCHRC_OK, { ENCH_DataNumber, { .dValue = 1234.5 } } },
+   { L"25/12/2020", ENCH_DataDate, ENCHRC_OK, { ENCH_DataDate, { .dateValue = { 25, 12, 2020 } } } },
+   { L"23:59:30", ENCH_DataTime, ENCHRC_OK, { ENCH_DataTime, { .timeValue = { 23, 59, 30 } } } },
+   { L"\x1b$💰 99,95", ENCH_DataCurrency, ENCHRC_OK, { ENCH_DataCurrency, { .dValue = 99.95 } } },
+   { L"forty-two", ENCH_DataInteger, ENCHRC_InvalidValue, { ENCH_DataNotSet, { 0 } } },
+};
+
+static const unsigned char fontGuid[16] =
+{
+   0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+   0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10
+};
+
+static const unsigned char styleGuid[16] =
+{
+   0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18,
+   0x19, 0x1A, 0x1B, 0x1C, 0x1D, 0x1E, 0x1F, 0x20
+};
+
+static void CheckCallback(EnchCallback* pCallback)
+{
+   CHECK(pCallback == &callback, "unexpected callback pointer %p", (void*)pCallback);
+   CHECK(pCallback->pEnchShellData == &shellData, "unexpected shell data %p", (void*)pCallback->pEnchShellData);
+}
+
+static void SetFontResource(EnchFontResource* pFontResource, const wchar_t* pszTypeface, const wchar_t* pszFileName, int nDeciPointSize, unsigned short fsFlags)
+{
+   memset(pFontResource, 0, sizeof(*pFontResource));
+   wcsncpy(pFontResource->szTypeface, pszTypeface, ENCH_TypefaceSize - 1);
+   wcsncpy(pFontResource->szFileName, pszFileName, ENCH_FileNameSize - 1);
+   pFontResource->nDeciPointSize = nDeciPointSize;
+   pFontResource->fsFlags = fsFlags;
+}
+
+static int GetFont(EnchCallback* pCallback, const unsigned char* pGuid, EnchFontResource* pFontResource)
+{
+   CheckCallback(pCallback);
+   if (!pGuid)
+   {
+      SetFontResource(pFontResource, L"Courier Ünïcode", L"", 100, 0);
+      return 1;
+   }
+   if (memcmp(pGuid, fontGuid, sizeof(fontGuid)) == 0)
+   {
+      SetFontResource(pFontResource, L"Noto Sans 😀 東京", szFontFileName, 125, ENCH_FontBold | ENCH_FontItalic);
+      return 1;
+   }
+   CHECK(0, "unexpected font GUID");
+   return 0;
+}
+
+static int GetStyle(EnchCallback* pCallback, const unsigned char* pGuid, EnchStyleResource* pStyleResource)
+{
+   CheckCallback(pCallback);
+   if (!pGuid || memcmp(pGuid, styleGuid, sizeof(styleGuid)) != 0)
+   {
+      CHECK(0, "unexpected style GUID");
+      return 0;
+   }
+   memset(pStyleResource, 0, sizeof(*pStyleResource));
+   SetFontResource(&pStyleResource->fontResource, L"Δelta", L"", 80, 0);
+   pStyleResource->color.master = ENCH_ColorRgb;
+   pStyleResource->color.red = 200;
+   pStyleResource->color.green = 100;
+   pStyleResource->color.blue = 50;
+   pStyleResource->fsFlags = ENCH_StyleUnderline;
+   return 1;
+}
+
+static ENCHRC GetDataValue(EnchCallback* pCallback, const wchar_t* pszValue, EnchDataValue* pDataValue, int nExpectedType)
+{
+   CheckCallback(pCallback);
+   for (size_t i = 0; i < sizeof(scriptedValues) / sizeof(scriptedValues[0]); i++)
+   {
+      const ScriptedValue* pScripted = &scriptedValues[i];
+      if (wcscmp(pszValue, pScripted->pszValue) == 0)
+      {
+         CHECK(nExpectedType == pScripted->nExpectedType, "value '%ls' expected type %d, want %d", pszValue, nExpectedType, pScripted->nExpectedType);
+         if (pScripted->rc == ENCHRC_OK)
+         {
+            *pDataValue = pScripted->dataValue;
+         }
+         return pScripted->rc;
+      }
+   }
+   CHECK(0, "unexpected value '%ls' (%zu wide characters)", pszValue, wcslen(pszValue));
+   return ENCHRC_InvalidValue;
+}
+
+static int GetNumberFormat(EnchCallback* pCallback, EnchNumberFormat* pNumberFormat)
+{
+   CheckCallback(pCallback);
+   pNumberFormat->chThousandsSeparator = L'\u00a0';
+   pNumberFormat->chDecimalPoint = L',';
+   return 1;
+}
+
+static wchar_t** WideArray(const wchar_t* const* ppsz, int csz)
+{
+   wchar_t** ppwsz = (wchar_t**)malloc(csz * sizeof(wchar_t*));
+   for (int i = 0; i < csz; i++)
+   {
+      ppwsz[i] = (wchar_t*)malloc((wcslen(ppsz[i]) + 1) * sizeof(wchar_t));
+      wcscpy(ppwsz[i], ppsz[i]);
+   }
+   return ppwsz;
+}
+
+static int GetDateTimeFormat(EnchCallback* pCallback, EnchDateTimeFormat* pDateTimeFormat)
+{
+   static const wchar_t* const monthNames[] =
+   {
+      L"janvier", L"février", L"mars", L"avril", L"mai", L"juin",
+      L"juillet", L"août", L"septembre", L"octobre", L"novembre", L"🎄 décembre"
+   };
+   static const wchar_t* const weekDayNames[] =
+   {
+      L"dimanche", L"lundi", L"mardi", L"mercredi", L"jeudi", L"vendredi", L"samedi"
+   };
+   static const wchar_t* const strings[] =
+   {
+      L"午前", L"午後", L"dd/MM/yyyy", L"dddd d MMMM yyyy", L"HH:mm:ss"
+   };
+
+   CheckCallback(pCallback);
+   wchar_t** ppszStrings = WideArray(strings, 5);
+   pDateTimeFormat->ppszMonthNames = WideArray(monthNames, 12);
+   pDateTimeFormat->cMonthNames = 12;
+   pDateTimeFormat->ppszWeekDayNames = WideArray(weekDayNames, 7);
+   pDateTimeFormat->cWeekDayNames = 7;
+   pDateTimeFormat->pszAm = ppszStrings[0];
+   pDateTimeFormat->pszPm = ppszStrings[1];
+   pDateTimeFormat->pszShortDateFormat = ppszStrings[2];
+   pDateTimeFormat->pszLongDateFormat = ppszStrings[3];
+   pDateTimeFormat->pszTimeFormat = ppszStrings[4];
+   free(ppszStrings);
+   cDateTimeFormats++;
+   return 1;
+}
+
+static void FreeWideArray(wchar_t** ppwsz, int cwsz)
+{
+   for (int i = 0; i < cwsz; i++)
+   {
+      free(ppwsz[i]);
+   }
+   free(ppwsz);
+}
+
+static void FreeDateTimeFormat(EnchDateTimeFormat* pDateTimeFormat)
+{
+   FreeWideArray(pDateTimeFormat->ppszMonthNames, pDateTimeFormat->cMonthNames);
+   FreeWideArray(pDateTimeFormat->ppszWeekDayNames, pDateTimeFormat->cWeekDayNames);
+   free(pDateTimeFormat->pszAm);
+   free(pDateTimeFormat->pszPm);
+   free(pDateTimeFormat->pszShortDateFormat);
+   free(pDateTimeFormat->pszLongDateFormat);
+   free(pDateTimeFormat->pszTimeFormat);
+   cFreedDateTimeFormats++;
+}
+
+//============================================================================
+// Test cases.
+//============================================================================
+
+static const char szProperties[] =
+   "engine=abi\n"
+   "config=test\n"
+   "title=\x10" "title\n"
+   "count=42\n"
+   "total=1\xc2\xa0" "234,5\n"
+   "date=25/12/2020\n"
+   "time=23:59:30\n"
+   "price=\x1b$💰 99,95\n"
+   "unknown=forty-two\n"
+   "font=\x1b" "f0102030405060708090A0B0C0D0E0F10|1,0,660510,0|true\n"
+   "style=\x1b" "f$1112131415161718191A1B1C1D1E1F20\n";
+
+static const char szSymbols[] = "title=Zürich – 東京 😀\n";
+
+static const char szExpectedImage[] =
+   "config=test\n"
+   "title=Zürich – 東京 😀\n"
+   "image=PNG CMYK 576000x432000 300\n"
+   "count=42\n"
+   "total=1234.5\n"
+   "date=2020-12-25\n"
+   "time=23:59:30\n"
+   "price=Currency 99.95\n"
+   "unknown=InvalidDataString\n"
+   "numberFormat='\\u00a0' ','\n"
+   "months=12 janvier 🎄 décembre\n"
+   "weekDays=7 dimanche\n"
+   "ampm=午前 午後\n"
+   "formats=dd/MM/yyyy|dddd d MMMM yyyy|HH:mm:ss\n"
+   "defaultFont=Courier Ünïcode 10 0  0,0,0 false\n"
+   "font=Noto Sans 😀 東京 12.5 3 %s/Gö Regular 😀.ttf 10,20,30 true\n"
+   "style=Δelta 8 0  200,100,50 true\n";
+
+static void TestCreateImage(PFNEnchCreateImage pfnCreateImage, PFNEnchDestroyImage pfnDestroyImage, const char* pszFontDir)
+{
+   char szExpected[sizeof(szExpectedImage) + ENCH_FileNameSize * 6];
+   snprintf(szExpected, sizeof(szExpected), szExpectedImage, pszFontDir);
+
+   EnchImage image;
+   memset(&image, 0, sizeof(image));
+   image.nFormat = ENCH_ImagePng;
+   image.nColorSpace = ENCH_ColorCmyk;
+   image.nLogWidth = 4 * 144000;
+   image.nLogHeight = 3 * 144000;
+   image.nRes = 300;
+
+   ENCHRC rc = pfnCreateImage(&callback, (const UTF8CHAR*)szProperties, (const UTF8CHAR*)szSymbols, &image);
+   CHECK(rc == ENCHRC_OK, "EnchCreateImage returned %d, want %d", rc, ENCHRC_OK);
+   CHECK(image.nFormat == ENCH_ImageSvg, "image format %d, want %d", image.nFormat, ENCH_ImageSvg);
+   CHECK(image.nColorSpace == ENCH_ColorRgb, "image colour space %d, want %d", image.nColorSpace, ENCH_ColorRgb);
+   CHECK(image.nLogWidth == 4 * 144000 && image.nLogHeight == 3 * 144000 && image.nRes == 300, "image size changed");
+   CHECK(cDateTimeFormats == 1, "date and time format requested %d times, want 1", cDateTimeFormats);
+   CHECK(cFreedDateTimeFormats == cDateTimeFormats, "date and time format freed %d times, want %d", cFreedDateTimeFormats, cDateTimeFormats);
+   if (rc != ENCHRC_OK)
+   {
+      return;
+   }
+   CHECK(image.pbImageData != NULL, "no image data");
+   if (image.cbImageData != strlen(szExpected) || memcmp(image.pbImageData, szExpected, image.cbImageData) != 0)
+   {
+      CHECK(0, "image data:\n%.*s\nwant:\n%s", (int)image.cbImageData, image.pbImageData, szExpected);
+   }
+
+   rc = pfnDestroyImage(&image);
+   CHECK(rc == ENCHRC_OK, "EnchDestroyImage returned %d, want %d", rc, ENCHRC_OK);
+}
+
+static void TestCreateImageError(PFNEnchCreateImage pfnCreateImage)
+{
+   EnchImage image;
+   memset(&image, 0, sizeof(image));
+   image.nFormat = ENCH_ImagePng;
+   image.nColorSpace = ENCH_ColorRgb;
+   image.nLogWidth = 144000;
+   image.nLogHeight = 144000;
+   image.nRes = 96;
+
+   ENCHRC rc = pfnCreateImage(&callback, (const UTF8CHAR*)"engine=abi\nconfig=test\n", (const UTF8CHAR*)"", &image);
+   CHECK(rc == ENCHRC_MissingProperty, "EnchCreateImage returned %d, want %d", rc, ENCHRC_MissingProperty);
+   CHECK(image.pbImageData == NULL && image.cbImageData == 0, "image data returned with error");
+}
+
+int main(int argc, char* argv[])
+{
+   if (argc != 3)
+   {
+      fprintf(stderr, "usage: %s <engine.so> <font-dir>\n", argv[0]);
+      return 2;
+   }
+   setlocale(LC_CTYPE, "C.UTF-8");
+
+   swprintf(szFontFileName, ENCH_FileNameSize, L"%s/Gö Regular 😀.ttf", argv[2]);
+
+   void* pvModule = dlopen(argv[1], RTLD_NOW | RTLD_LOCAL);
+   if (!pvModule)
+   {
+      fprintf(stderr, "dlopen: %s\n", dlerror());
+      return 2;
+   }
+   PFNEnchCreateImage pfnCreateImage = (PFNEnchCreateImage)dlsym(pvModule, "EnchCreateImage");
+   PFNEnchDestroyImage pfnDestroyImage = (PFNEnchDestroyImage)dlsym(pvModule, "EnchDestroyImage");
+   PFNEnchTerminate pfnTerminate = (PFNEnchTerminate)dlsym(pvModule, "EnchTerminate");
+   if (!pfnCreateImage || !pfnDestroyImage || !pfnTerminate)
+   {
+      fprintf(stderr, "dlsym: %s\n", dlerror());
+      return 2;
+   }
+
+   callback.pEnchShellData = &shellData;
+   callback.pfnGetFont = GetFont;
+   callback.pfnGetStyle = GetStyle;
+   callback.pfnGetDataValue = GetDataValue;
+   callback.pfnGetNumberFormat = GetNumberFormat;
+   callback.pfnGetDateTimeFormat = GetDateTimeFormat;
+   callback.pfnFreeDateTimeFormat = FreeDateTimeFormat;
+
+   TestCreateImage(pfnCreateImage, pfnDestroyImage, argv[2]);
+   TestCreateImageError(pfnCreateImage);
+
+   // The module must stay loaded as the Go runtime cannot be unloaded.
+   ENCHRC rc = pfnTerminate();
+   CHECK(rc == ENCHRC_Failed, "EnchTerminate returned %d, want %d", rc, ENCHRC_Failed);
+
+   if (cFailures > 0)
+   {
+      fprintf(stderr, "FAIL: %d checks failed\n", cFailures);
+      return 1;
+   }
+   printf("PASS\n");
+   return 0;
+}
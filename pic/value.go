@@ -1,6 +1,6 @@
 package pic
 
-import "log"
+import "log/slog"
 
 // Value represents a value of a property from the configuration.
 type Value string
@@ -20,7 +20,7 @@ func (v Value) Type() DataType {
 		case '$':
 			return Currency
 		default:
-			log.Printf("Unrecognised value type '%c'\n", v[1])
+			rootLogger().Error("Unrecognised value type", slog.String("type", string(v[1])))
 			return NotSet
 		}
 	}